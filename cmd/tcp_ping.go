@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -19,41 +21,125 @@ import (
 const (
 	envVarPrefix           = "TCP_PING_"
 	invalidTaskURLTemplate = "invalid task URL: %s"
+	invalidDeadline        = "invalid deadline: %s"
+	invalidTasks           = "invalid prober tasks were configured"
+	invalidLimit           = "invalid %s: %s"
 )
 
-func startProbing(ctx context.Context, wg *sync.WaitGroup, pp *prober.Prober) {
+const (
+	// like `ping`: 1 when the targets misbehaved, 2 on any other error
+	exitCodeCriteriaFailed = 1
+	exitCodeInvalidConfig  = 2
+)
+
+const (
+	// stop all tasks after this long ( duration; bare integers are seconds )
+	deadlineEnvVar = envVarPrefix + "DEADLINE"
+	// max number of probes per second across all tasks
+	rateLimitEnvVar = envVarPrefix + "RATE_LIMIT"
+	// max number of concurrent probes across all tasks
+	maxInFlightEnvVar = envVarPrefix + "MAX_IN_FLIGHT"
+)
+
+// environment variables that configure tcp_ping itself instead of describing a task
+var reservedEnvVars = map[string]bool{
+	deadlineEnvVar:    true,
+	rateLimitEnvVar:   true,
+	maxInFlightEnvVar: true,
+}
+
+func startProbing(ctx context.Context, wg *sync.WaitGroup, pp *prober.Prober, limiter *prober.Limiter, failed *atomic.Bool) {
 	p := *pp
 	start := time.Now()
-	count := prober.Probe(ctx, pp)
+	count := prober.Probe(ctx, pp, limiter)
 	executionTime := time.Since(start)
 	fmt.Println(stringFormatter.Format("Probed '{0}' {1} times ( {2} )", *p.RawURL(), count, executionTime))
+	if !prober.Verdict(pp) {
+		failed.Store(true)
+	}
 	wg.Done()
 }
 
-func provideProbers() (probers []*prober.Prober) {
+// provideProbers creates the probers of all tasks; all invalid task URLs are logged
+// before reporting them, so all of them can be fixed at once.
+func provideProbers() (probers []*prober.Prober, invalid bool) {
 	var err error
 	defer err2.Handle(&err)
 	for _, e := range os.Environ() {
 		pair := strings.SplitN(e, "=", 2)
-		if strings.HasPrefix(pair[0], envVarPrefix) {
-			prober := try.Out1(prober.NewProberFromRawURL(&pair[1])).
+		if strings.HasPrefix(pair[0], envVarPrefix) && !reservedEnvVars[pair[0]] {
+			taskProbers := try.Out1(prober.NewProbersFromRawURL(&pair[1])).
 				Logf(invalidTaskURLTemplate, pair[1]).Catch(nil)
-			if prober != nil {
-				probers = append(probers, prober)
-			}
+			// valid task URLs provide at least 1 prober
+			invalid = invalid || len(taskProbers) == 0
+			probers = append(probers, taskProbers...)
+		}
+	}
+	return probers, invalid
+}
+
+func getDeadline() (time.Duration, error) {
+	rawDeadline := os.Getenv(deadlineEnvVar)
+	if rawDeadline == "" {
+		return 0, nil
+	}
+	if seconds, err := strconv.Atoi(rawDeadline); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	return time.ParseDuration(rawDeadline)
+}
+
+func provideLimiter() (*prober.Limiter, error) {
+	var rate float64
+	var maxInFlight uint64
+	var err error
+
+	if rawRate := os.Getenv(rateLimitEnvVar); rawRate != "" {
+		if rate, err = strconv.ParseFloat(rawRate, 64); err != nil || rate < 0 {
+			return nil, fmt.Errorf(invalidLimit, rateLimitEnvVar, rawRate)
+		}
+	}
+	if rawMaxInFlight := os.Getenv(maxInFlightEnvVar); rawMaxInFlight != "" {
+		if maxInFlight, err = strconv.ParseUint(rawMaxInFlight, 10, 64); err != nil {
+			return nil, fmt.Errorf(invalidLimit, maxInFlightEnvVar, rawMaxInFlight)
 		}
 	}
-	return probers
+
+	if rate == 0 && maxInFlight == 0 {
+		return nil, nil
+	}
+	return prober.NewLimiter(rate, maxInFlight), nil
 }
 
 func main() {
-	probers := provideProbers()
+	probers, invalid := provideProbers()
+	// a task with a typo ( i/e: in its success criteria ) must not pass silently
+	if invalid {
+		fmt.Println(invalidTasks)
+		os.Exit(exitCodeInvalidConfig)
+	}
 	if len(probers) == 0 {
 		fmt.Println("no prober tasks were configured")
 		os.Exit(0)
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if deadline, err := getDeadline(); err != nil || deadline < 0 {
+		fmt.Printf(invalidDeadline+"\n", os.Getenv(deadlineEnvVar))
+		os.Exit(exitCodeInvalidConfig)
+	} else if deadline > 0 {
+		ctx, cancel = context.WithTimeout(ctx, deadline)
+		defer cancel()
+	}
+
+	limiter, err := provideLimiter()
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(exitCodeInvalidConfig)
+	}
+
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
@@ -62,12 +148,37 @@ func main() {
 		cancel()
 	}()
 
+	// `SIGUSR1` triggers an on-demand traceroute for every task
+	traceChan := make(chan os.Signal, 1)
+	signal.Notify(traceChan, syscall.SIGUSR1)
+
+	go func() {
+		for range traceChan {
+			for _, task := range probers {
+				prober.Traceroute(task)
+			}
+		}
+	}()
+
 	var wg sync.WaitGroup
+	var failed atomic.Bool
 	for _, task := range probers {
 		wg.Add(1)
-		go startProbing(ctx, &wg, task)
+		go startProbing(ctx, &wg, task, limiter, &failed)
 	}
 
-	<-ctx.Done()
+	// tasks stop on their own when bounded by `max_probes`, `max_duration` or the deadline
 	wg.Wait()
+
+	// final stats and verdicts must reach the log files before exiting
+	for _, task := range probers {
+		if err := prober.Close(task); err != nil {
+			fmt.Println(err.Error())
+		}
+	}
+
+	// at least one task did not meet its success criteria
+	if failed.Load() {
+		os.Exit(exitCodeCriteriaFailed)
+	}
 }
@@ -0,0 +1,345 @@
+package prober
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net/netip"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+type (
+	pmtuOutcome uint8
+
+	pmtuDiscovery struct {
+		MTU         int
+		RouteMTU    int
+		PreviousMTU int
+		Changed     bool
+		BlackHole   bool
+		Probes      uint16
+		LocalAddr   string
+	}
+
+	PMTUProberTask struct {
+		proberTask
+		protocol string
+		lastMTU  int
+	}
+)
+
+const (
+	pmtuDelivered pmtuOutcome = iota
+	pmtuTooBig
+	pmtuLost
+)
+
+const (
+	PMTU_PROTOCOL_UDP  = "udp"
+	PMTU_PROTOCOL_ICMP = "icmp"
+
+	ipv4HeaderSize = 20
+	ipv6HeaderSize = 40
+	l4HeaderSize   = 8 // both UDP and ICMP echo headers are 8 bytes long
+
+	minIPv4MTU = 576
+	minIPv6MTU = 1280
+
+	icmpv4PortUnreachable uint8 = 3
+	icmpv4FragNeeded      uint8 = 4
+	icmpv6PacketTooBig    uint8 = 2
+	icmpv6PortUnreachable uint8 = 4
+	icmpv4EchoRequest     byte  = 8
+	icmpv6EchoRequest     byte  = 128
+
+	// longest wait for evidence of a packet before checking whether probing is over
+	pmtuPollInterval = 100 * time.Millisecond
+)
+
+var errorPMTUNotDelivered = errors.New("no probe packet was delivered")
+
+func newPMTUProberTask(task *proberTask) Prober {
+	return &PMTUProberTask{*task, task.Params.PMTUProtocol, 0}
+}
+
+func (p *PMTUProberTask) newSocket(target *netip.AddrPort) (fd int, err error) {
+	family, level := unix.AF_INET, unix.IPPROTO_IP
+	discover, recvErr := unix.IP_MTU_DISCOVER, unix.IP_RECVERR
+	probeMode := unix.IP_PMTUDISC_PROBE
+	sockaddr := unix.Sockaddr(&unix.SockaddrInet4{Port: int(target.Port()), Addr: target.Addr().As4()})
+	proto := unix.IPPROTO_ICMP
+
+	if target.Addr().Is6() {
+		family, level = unix.AF_INET6, unix.IPPROTO_IPV6
+		discover, recvErr = unix.IPV6_MTU_DISCOVER, unix.IPV6_RECVERR
+		probeMode = unix.IPV6_PMTUDISC_PROBE
+		sockaddr = &unix.SockaddrInet6{Port: int(target.Port()), Addr: target.Addr().As16()}
+		proto = unix.IPPROTO_ICMPV6
+	}
+
+	if p.protocol != PMTU_PROTOCOL_ICMP {
+		proto = unix.IPPROTO_UDP
+	}
+
+	// ICMP requires unprivileged ping sockets: see `net.ipv4.ping_group_range`
+	fd, err = unix.Socket(family, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, proto)
+	if err != nil {
+		return -1, err
+	}
+
+	// `*_PMTUDISC_PROBE` sets the DF flag while ignoring the cached path MTU,
+	// so that every discovery measures the path instead of the kernel's view of it.
+	err = errors.Join(
+		unix.SetsockoptInt(fd, level, discover, probeMode),
+		unix.SetsockoptInt(fd, level, recvErr, 1),
+		bindToDevice(&p.proberTask, uintptr(fd)),
+		setTrafficOptions(&p.proberTask, uintptr(fd), target.Addr().Is6()),
+		p.bindSourceIP(fd),
+	)
+	if err == nil {
+		err = unix.Connect(fd, sockaddr)
+	}
+	if err != nil {
+		unix.Close(fd)
+		return -1, err
+	}
+	return fd, nil
+}
+
+func (p *PMTUProberTask) bindSourceIP(fd int) error {
+	sourceIP := p.Params.SourceIP
+	switch {
+	case !sourceIP.IsValid():
+		return nil
+	case sourceIP.Is4():
+		return unix.Bind(fd, &unix.SockaddrInet4{Addr: sourceIP.As4()})
+	default:
+		return unix.Bind(fd, &unix.SockaddrInet6{Addr: sourceIP.As16()})
+	}
+}
+
+func localAddr(fd int) string {
+	sockaddr, err := unix.Getsockname(fd)
+	if err != nil {
+		return ""
+	}
+	switch sa := sockaddr.(type) {
+	case *unix.SockaddrInet4:
+		return netip.AddrPortFrom(netip.AddrFrom4(sa.Addr), uint16(sa.Port)).String()
+	case *unix.SockaddrInet6:
+		return netip.AddrPortFrom(netip.AddrFrom16(sa.Addr), uint16(sa.Port)).String()
+	}
+	return ""
+}
+
+func (p *PMTUProberTask) routeMTU(fd int, IPv6 bool) (int, error) {
+	if IPv6 {
+		return unix.GetsockoptInt(fd, unix.IPPROTO_IPV6, unix.IPV6_MTU)
+	}
+	return unix.GetsockoptInt(fd, unix.IPPROTO_IP, unix.IP_MTU)
+}
+
+func (p *PMTUProberTask) newPayload(size int, IPv6 bool) []byte {
+	headers := ipv4HeaderSize + l4HeaderSize
+	if IPv6 {
+		headers = ipv6HeaderSize + l4HeaderSize
+	}
+	if p.protocol != PMTU_PROTOCOL_ICMP {
+		return make([]byte, size-headers)
+	}
+	// ping sockets expect the ICMP header: the kernel fills in the identifier and the checksum
+	payload := make([]byte, size-headers+l4HeaderSize)
+	payload[0] = icmpv4EchoRequest
+	if IPv6 {
+		payload[0] = icmpv6EchoRequest
+	}
+	binary.BigEndian.PutUint16(payload[6:8], uint16(size))
+	return payload
+}
+
+// probeSize sends a single DF-flagged packet of `size` bytes ( IP headers included )
+// and waits for evidence of it being delivered or rejected by the path; waiting stops
+// as soon as `ctx` is done, which is only reported by `ctx.Err()` afterwards.
+func (p *PMTUProberTask) probeSize(ctx context.Context, fd int, size int, IPv6 bool, timeout time.Duration) (pmtuOutcome, int) {
+	if err := unix.Send(fd, p.newPayload(size, IPv6), 0); err != nil {
+		if errors.Is(err, syscall.EMSGSIZE) {
+			return pmtuTooBig, 0
+		}
+		return pmtuLost, 0
+	}
+
+	deadline := time.Now().Add(timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	buffer := make([]byte, size)
+	for {
+		wait := time.Until(deadline)
+		if wait <= 0 || ctx.Err() != nil {
+			return pmtuLost, 0
+		}
+		// poll does not know about `ctx`: cancellation is checked in between short waits
+		wait = min(wait, pmtuPollInterval)
+
+		fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}
+		n, err := unix.Poll(fds, int(wait.Milliseconds())+1)
+		if errors.Is(err, syscall.EINTR) {
+			continue
+		}
+		if err != nil || n == 0 {
+			return pmtuLost, 0
+		}
+
+		if fds[0].Revents&unix.POLLERR != 0 {
+			oob := make([]byte, 512)
+			_, oobn, _, _, err := unix.Recvmsg(fd, nil, oob, unix.MSG_ERRQUEUE|unix.MSG_DONTWAIT)
+			if err != nil {
+				continue
+			}
+			icmpType, icmpCode, mtu := readPMTUError(oob[:oobn])
+			switch {
+			case !IPv6 && icmpType == icmpv4DestUnreachable && icmpCode == icmpv4FragNeeded,
+				IPv6 && icmpType == icmpv6PacketTooBig:
+				return pmtuTooBig, mtu
+			case !IPv6 && icmpType == icmpv4DestUnreachable && icmpCode == icmpv4PortUnreachable,
+				IPv6 && icmpType == icmpv6DestUnreachable && icmpCode == icmpv6PortUnreachable:
+				// the packet made it to the target, which just didn't have anything listening
+				return pmtuDelivered, 0
+			}
+			continue
+		}
+
+		if fds[0].Revents&unix.POLLIN != 0 {
+			if _, _, err := unix.Recvfrom(fd, buffer, unix.MSG_DONTWAIT); err == nil {
+				return pmtuDelivered, 0
+			}
+		}
+	}
+}
+
+// readPMTUError parses the `IP_RECVERR` control message: for `fragmentation needed`
+// and `packet too big` errors, the kernel reports the next-hop MTU as `ee_info`.
+func readPMTUError(oob []byte) (icmpType, icmpCode uint8, mtu int) {
+	messages, err := unix.ParseSocketControlMessage(oob)
+	if err != nil {
+		return 0, 0, 0
+	}
+	for _, message := range messages {
+		if len(message.Data) < sizeOfSockExtendedErr {
+			continue
+		}
+		origin := message.Data[4]
+		if origin != unix.SO_EE_ORIGIN_ICMP && origin != unix.SO_EE_ORIGIN_ICMP6 {
+			continue
+		}
+		return message.Data[5], message.Data[6], int(binary.NativeEndian.Uint32(message.Data[8:12]))
+	}
+	return 0, 0, 0
+}
+
+func (p *PMTUProberTask) discover(ctx context.Context, target *netip.AddrPort) (*pmtuDiscovery, error) {
+	IPv6 := target.Addr().Is6()
+
+	fd, err := p.newSocket(target)
+	if err != nil {
+		return nil, err
+	}
+	defer unix.Close(fd)
+
+	discovery := &pmtuDiscovery{PreviousMTU: p.lastMTU, LocalAddr: localAddr(fd)}
+
+	routeMTU, err := p.routeMTU(fd, IPv6)
+	if err != nil {
+		return nil, err
+	}
+	discovery.RouteMTU = routeMTU
+
+	low, high := minIPv4MTU, routeMTU
+	if IPv6 {
+		low = minIPv6MTU
+	}
+	if maxMTU := int(p.Params.PMTUMax); maxMTU > 0 {
+		high = maxMTU
+	}
+	if high < low {
+		high = low
+	}
+
+	timeout := p.Params.Timeout
+	delivered := false
+	largestLost := 0
+
+	// the largest size is tried first as it's the most common outcome,
+	// then the range is narrowed with a binary search.
+	size := high
+	for low <= high {
+		outcome, mtu := p.probeSize(ctx, fd, size, IPv6, timeout)
+		if ctx.Err() != nil {
+			// the outcome of the interrupted size says nothing about the path
+			return discovery, ctx.Err()
+		}
+		discovery.Probes += 1
+
+		switch outcome {
+		case pmtuDelivered:
+			delivered = true
+			low = size + 1
+			discovery.MTU = size
+		case pmtuTooBig:
+			high = size - 1
+			if mtu > 0 && mtu < high {
+				high = mtu
+			}
+		case pmtuLost:
+			high = size - 1
+			if size > largestLost {
+				largestLost = size
+			}
+		}
+
+		size = low + (high-low+1)/2
+		if outcome == pmtuTooBig && high >= low {
+			// the reported MTU is the best candidate for the next attempt
+			size = high
+		}
+	}
+
+	if !delivered {
+		return discovery, errorPMTUNotDelivered
+	}
+
+	// bigger packets silently vanished while smaller ones made it through: no ICMP feedback
+	discovery.BlackHole = largestLost > discovery.MTU
+	discovery.Changed = p.lastMTU != 0 && p.lastMTU != discovery.MTU
+	p.lastMTU = discovery.MTU
+
+	return discovery, nil
+}
+
+func (p *PMTUProberTask) probe(ctx context.Context, attempt *uint64) (*time.Duration, error) {
+	target, err := p.beforeProbing(ctx, attempt)
+	if err != nil {
+		target = p.Target
+	}
+
+	start := time.Now()
+	discovery, err := p.discover(ctx, target)
+	latency := time.Since(start)
+
+	data := &proberTaskData{latency: &latency, discovery: true}
+	if discovery != nil {
+		data.localAddr = discovery.LocalAddr
+	}
+
+	p.afterProbing(ctx, attempt, target, data, err)
+
+	// discoveries interrupted because probing is over say nothing about the path
+	if discovery != nil && (err == nil || !isOver(ctx)) {
+		(*p.Printer).printPMTU(&p.proberTask, attempt, target, discovery, err)
+	}
+
+	return &latency, err
+}
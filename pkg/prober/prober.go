@@ -1,7 +1,6 @@
 package prober
 
 import (
-	"container/ring"
 	"context"
 	"errors"
 	"log"
@@ -10,14 +9,16 @@ import (
 	"net/netip"
 	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
-	"github.com/go-toolbelt/jitter"
 	"github.com/lainio/err2"
 	"github.com/lainio/err2/try"
 	errorx "github.com/pkg/errors"
-	"gonum.org/v1/gonum/stat"
+	"github.com/wissance/stringFormatter"
 )
 
 type (
@@ -27,24 +28,45 @@ type (
 	logSizeType = uint16
 
 	proberTaskData struct {
-		latency *time.Duration
+		latency   *time.Duration
+		localAddr string
+		tcpInfo   *tcpInfo
+		synLoss   *synLoss
+
+		closeLatency *time.Duration
+		closeErr     error
+
+		flowPort uint16
+
+		connection uint64 // which connection the probe used, for `persistent` probes
+
+		// the probe discovered a property of the path ( i/e: its MTU or idle timeout ):
+		// its duration is not a latency, so it is kept out of all latency stats
+		discovery bool
+
+		timing *probeTiming
+
+		// latency and its change as observed when the probe completed
+		rtt   float64
+		delta float64
 	}
 
 	proberTaskStats struct {
-		TotalProbes          uint64
-		TotalSuccessful      uint64
-		TotalFailures        uint64
-		ConsecutiveSuccesful uint64
-		ConsecutiveFailures  uint64
-		LastLatency          float64
-		DeltaLatency         float64
-		OverallMinLatency    float64
-		OverallMaxLatency    float64
-		MinLatency           float64
-		MaxLatency           float64
-		AverageLatency       float64
-		StandardDeviation    float64
-		Skewness             float64
+		TotalProbes            uint64
+		TotalSuccessful        uint64
+		TotalFailures          uint64
+		ConsecutiveSuccesful   uint64
+		ConsecutiveFailures    uint64
+		LastLatency            float64
+		DeltaLatency           float64
+		OverallMinLatency      float64
+		OverallMaxLatency      float64
+		Window                 latencyStats    // last `log_size` probes: failures account for how long they took ( `probe_timeout` if they timed out )
+		Successful             latencyStats    // last `log_size` probes, only successful ones
+		Rolling                []*rollingStats // wall-clock windows ending when stats were printed
+		SYNLossRecovered       uint64
+		MaxConsecutiveFailures uint64
+		MissedProbes           uint64 // slots passed without probing
 	}
 
 	proberTask struct {
@@ -58,20 +80,48 @@ type (
 		Target    *netip.AddrPort
 		Params    *proberTaskParams
 		Stats     *proberTaskStats
-		Latencies *ring.Ring
+		Latencies *latencyRing
+		Sketch    *proberTaskSketch
+		Rolling   *proberTaskRolling
 		Printer   *probePrinter
+		Flows     *proberTaskFlows
+		Group     *proberTaskGroup
+		Schedule  *proberSchedule
+
+		// guards stats and target: `overlap` probes complete concurrently
+		mutex     *sync.Mutex
+		sequencer *probeSequencer
+
+		groupMember        int
+		tracing            *atomic.Bool
+		onDemandTraceroute chan struct{}
 	}
 
 	probePrinter interface {
-		printProbe(*proberTask, *uint64, *netip.AddrPort, *time.Duration, error)
+		printProbe(*proberTask, *uint64, *netip.AddrPort, *proberTaskData, error)
 		printStats(*proberTask, *logSizeType)
 		printDNSUpdate(*proberTask, *time.Duration, *netip.Addr, bool, error)
+		printTraceroute(*proberTask, *netip.AddrPort, []*tracerouteHop, string)
+		printPMTU(*proberTask, *uint64, *netip.AddrPort, *pmtuDiscovery, error)
+		printConnectionEvent(*proberTask, *netip.AddrPort, *connectionEvent)
+		printIdleTimeout(*proberTask, *uint64, *netip.AddrPort, *idleTimeoutDiscovery, error)
+		printBurst(*proberTask, *uint64, *netip.AddrPort, *burstResult)
+		printGroupStats(*proberTask, *proberTaskGroup)
+		printVerdict(*proberTask, *proberVerdict)
+		printIntervalChange(*proberTask, *uint64, *intervalChange)
+		// close flushes all records; printers shared by a group's tasks may be closed by all of them
+		close() error
 	}
 
 	Prober interface {
 		probe(context.Context, *uint64) (*time.Duration, error)
 		printStats()
-		interval() *time.Duration
+		traceroute(context.Context, string)
+		tracerouteRequests() chan struct{}
+		schedule() *proberSchedule
+		params() *proberTaskParams
+		verdict() *proberVerdict
+		close() error
 		RawURL() *string
 		T() ProberType
 	}
@@ -86,6 +136,7 @@ const (
 	HTTP_IPv6
 	HTTPS_IPv4
 	HTTPS_IPv6
+	UNIX
 )
 
 const (
@@ -97,55 +148,57 @@ const (
 	HTTPS_IPv4_SCHEME string = "https+ipv4"
 	HTTP_IPv6_SCHEME  string = "http+ipv6"
 	HTTPS_IPv6_SCHEME string = "https+ipv6"
+	UNIX_SCHEME       string = "unix"
 )
 
 var (
 	errorUnknownTaskType      = errorx.New("unknown task type")
 	errorUnknownHostname      = errorx.New("unknown hostname")
 	errorDNSUpdateNotRequired = errorx.New("DNS refresh is not required")
+	errorUnexpectedResponse   = errorx.New("unexpected response")
+	errorInvalidPorts         = errorx.New("invalid ports")
+	errorInvalidAddrs         = errorx.New("invalid addresses")
+	errorTooManyTargets       = errorx.New("too many targets")
+	errorInvalidParam         = errorx.New("invalid parameter")
 )
 
 var logrotateLogger = log.New(os.Stderr, "logrotate", log.LstdFlags)
 
 func (pt *proberTask) printStats() {
-	params := pt.Params
-	stats := pt.Stats
+	pt.mutex.Lock()
+	defer pt.mutex.Unlock()
 
-	size := params.LogSize
-	if stats.TotalProbes <= uint64(size) {
-		size = uint16(stats.TotalProbes)
-	}
-	latencies := make([]float64, size)
+	stats := pt.Stats
 
-	var count logSizeType = 0
-	var totalLatency float64 = 0.0
-	pt.Latencies.Do(func(rtt any) {
-		if rtt == nil {
-			return
-		}
+	stats.MissedProbes = pt.Schedule.missedProbes()
 
-		latency := rtt.(float64)
-		if latency >= stats.MaxLatency {
-			stats.MaxLatency = latency
-		}
-		if latency <= stats.MinLatency {
-			stats.MinLatency = latency
-		}
-		totalLatency += latency
+	// window stats are computed from scratch: they only account for the last `log_size` probes
+	latencies := pt.Latencies.latencies(false)
+	stats.Window = newLatencyStats(latencies)
+	stats.Successful = newLatencyStats(pt.Latencies.latencies(true))
 
-		latencies[count] = latency
+	if pt.Rolling != nil {
+		stats.Rolling = pt.Rolling.evaluate(time.Now())
+	}
 
-		count += 1
-		latencies = append(latencies, latency)
-	})
+	if pt.Flows != nil {
+		pt.Flows.evaluate()
+	}
 
-	stats.StandardDeviation = stat.StdDev(latencies, nil)
-	stats.Skewness = stat.Skew(latencies, nil)
-	stats.AverageLatency = totalLatency / float64(count)
+	pt.Sketch.fold()
+	pt.Sketch.window(latencies)
 
+	count := logSizeType(pt.Latencies.len())
 	(*pt.Printer).printStats(pt, &count)
 
-	latencies = nil
+	if pt.Flows != nil {
+		pt.Flows.reset()
+	}
+
+	// the first member of the group reports on behalf of all of them
+	if pt.Group != nil && pt.groupMember == 0 {
+		pt.Group.printStats(pt)
+	}
 }
 
 func (pt *proberTask) resolveHostname(ctx context.Context) (netip.Addr, time.Duration, error) {
@@ -189,7 +242,7 @@ func (pt *proberTask) getIPForAttempt(ctx context.Context, attempt *uint64) (IP
 	IP = pt.IP
 	requiresUpdate = false
 
-	if taskType == RAW_IPv4 || taskType == RAW_IPv6 {
+	if taskType == RAW_IPv4 || taskType == RAW_IPv6 || taskType == UNIX {
 		return IP, 0, false, errorx.WithMessage(errorDNSUpdateNotRequired, "not DNS prober")
 	}
 
@@ -197,6 +250,10 @@ func (pt *proberTask) getIPForAttempt(ctx context.Context, attempt *uint64) (IP
 		return IP, 0, false, errorx.WithMessage(errorDNSUpdateNotRequired, "IP is still valid")
 	}
 
+	if pt.Group != nil {
+		return pt.Group.resolve(ctx, pt, att)
+	}
+
 	IP, latency, err = pt.resolveHostname(ctx)
 	return IP, latency, true, err
 }
@@ -211,6 +268,9 @@ func (pt *proberTask) getTargetForAttempt(ctx context.Context, attempt *uint64)
 		pt.IP = IP
 	} else if requiresUpdate && err != nil {
 		p.printDNSUpdate(pt, &latency, &IP, requiresUpdate, err)
+	} else if err == nil {
+		// another member of the group already refreshed the shared IP
+		pt.IP = IP
 	}
 
 	target := netip.AddrPortFrom(pt.IP, pt.Port)
@@ -220,7 +280,13 @@ func (pt *proberTask) getTargetForAttempt(ctx context.Context, attempt *uint64)
 func (pt *proberTask) beforeProbing(ctx context.Context, attempt *uint64) (*netip.AddrPort, error) {
 	att := *attempt
 
+	pt.mutex.Lock()
 	target, err := pt.getTargetForAttempt(ctx, attempt)
+	if err == nil {
+		pt.Target = target
+	}
+	pt.mutex.Unlock()
+
 	if err != nil {
 		return nil, err
 	}
@@ -231,60 +297,140 @@ func (pt *proberTask) beforeProbing(ctx context.Context, attempt *uint64) (*neti
 		pt.printStats()
 	}
 
-	pt.Target = target
-
 	return target, nil
 }
 
+// isOver tells whether `ctx` is done: dialers may give up on its deadline
+// slightly before `ctx` itself reports to be done.
+func isOver(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return true
+	}
+	deadline, ok := ctx.Deadline()
+	return ok && !time.Now().Before(deadline)
+}
+
 func (pt *proberTask) afterProbing(ctx context.Context,
-	attempt *uint64, target *netip.AddrPort, latency *time.Duration, err error,
+	attempt *uint64, target *netip.AddrPort, data *proberTaskData, err error,
 ) {
 	att := *attempt
+	latency := data.latency
+
+	pt.mutex.Lock()
+	defer pt.mutex.Unlock()
+
+	data.timing = pt.Schedule.timing(att)
+
+	// probes interrupted because probing is over say nothing about the target
+	if err != nil && isOver(ctx) {
+		pt.sequencer.emit(att, nil)
+		return
+	}
 
 	timeout := pt.Params.Timeout
 
-	if errors.Is(err, context.DeadlineExceeded) || *latency >= timeout {
+	if !data.discovery && (errors.Is(err, context.DeadlineExceeded) || *latency >= timeout) {
 		*latency = timeout
 	}
 
 	// this is not RTT in the same sence of `ping`
 	rtt := asMillis(latency)
-
-	pt.Latencies.Value = rtt
-	pt.Latencies = pt.Latencies.Next()
+	data.rtt = rtt
 
 	stats := pt.Stats
 
-	// update last observed latency with current observation
-	stats.DeltaLatency = stats.LastLatency - rtt
-	stats.LastLatency = rtt
+	if !data.discovery {
+		pt.Latencies.add(rtt, err == nil)
+		pt.Sketch.observe(rtt)
+		if pt.Rolling != nil {
+			pt.Rolling.observe(time.Now(), rtt, err)
+		}
 
-	// update total number of probes performed
-	stats.TotalProbes = att
+		// update last observed latency with current observation
+		stats.DeltaLatency = stats.LastLatency - rtt
+		stats.LastLatency = rtt
+		data.delta = stats.DeltaLatency
 
-	// upodate overall min/max latencies
-	if rtt >= stats.OverallMaxLatency {
-		stats.OverallMaxLatency = rtt
-	}
-	if rtt <= stats.OverallMinLatency {
-		stats.OverallMinLatency = rtt
+		// upodate overall min/max latencies
+		if rtt >= stats.OverallMaxLatency {
+			stats.OverallMaxLatency = rtt
+		}
+		if rtt <= stats.OverallMinLatency {
+			stats.OverallMinLatency = rtt
+		}
 	}
 
+	// update total number of probes performed: overlapping probes may complete out of order
+	stats.TotalProbes = max(stats.TotalProbes, att)
+
 	if err != nil {
 		stats.TotalFailures += 1
 		stats.ConsecutiveFailures += 1
 		stats.ConsecutiveSuccesful = 0
+		stats.MaxConsecutiveFailures = max(stats.MaxConsecutiveFailures, stats.ConsecutiveFailures)
 	} else {
 		stats.TotalSuccessful += 1
 		stats.ConsecutiveSuccesful += 1
 		stats.ConsecutiveFailures = 0
 	}
 
-	(*pt.Printer).printProbe(pt, attempt, target, latency, err)
+	if pt.Group != nil && !data.discovery {
+		pt.Group.observe(pt.groupMember, rtt, err)
+	}
+
+	if pt.Flows != nil && data.flowPort != 0 {
+		pt.Flows.observe(data.flowPort, rtt, err)
+	}
+
+	// packet loss signal: the connection was established only after retransmitting
+	if data.synLoss != nil {
+		stats.SYNLossRecovered += 1
+	}
+
+	pt.sequencer.emit(att, func() {
+		(*pt.Printer).printProbe(pt, &att, target, data, err)
+	})
+
+	pt.adaptInterval(attempt)
+
+	tracerouteAfter := uint64(pt.Params.TracerouteAfter)
+	if tracerouteAfter > 0 && stats.ConsecutiveFailures == tracerouteAfter {
+		pt.traceTo(ctx, *target, TRACEROUTE_TRIGGER_FAILURES)
+	}
 }
 
-func (pt *proberTask) interval() *time.Duration {
-	return &pt.Params.Interval
+// host identifies the task's target as provided by the task URL
+func (pt *proberTask) host() string {
+	if pt.Type == UNIX {
+		return pt.URL.Path
+	}
+	return pt.URL.Host
+}
+
+// address renders the target of a probe: unix sockets are not addressed by IP and port
+func (pt *proberTask) address(target *netip.AddrPort) string {
+	if pt.Type == UNIX {
+		return pt.URL.Path
+	}
+	return target.String()
+}
+
+func (pt *proberTask) schedule() *proberSchedule {
+	return pt.Schedule
+}
+
+func (pt *proberTask) params() *proberTaskParams {
+	return pt.Params
+}
+
+func (pt *proberTask) close() error {
+	return (*pt.Printer).close()
+}
+
+// Close flushes all records printed by the prober; it must be called once probing is over,
+// as records written to log files are queued and would be lost when exiting.
+func Close(prober *Prober) error {
+	return (*prober).close()
 }
 
 func (pt *proberTask) RawURL() *string {
@@ -295,65 +441,276 @@ func (pt *proberTask) T() ProberType {
 	return pt.Type
 }
 
-func Probe(ctx context.Context, prober *Prober) uint64 {
+// Probe runs the prober until `ctx` is done, or until the task's
+// `max_probes` or `max_duration` is reached; it returns how many probes were sent.
+// When the task has a `schedule`, probes are only sent while its windows are open;
+// `limiter` ( which may be `nil` ) delays probes to honor process-wide limits.
+// With `concurrency=overlap`, up to `max_in_flight` probes run at the same time;
+// otherwise slots passed while a probe is running are missed.
+func Probe(ctx context.Context, prober *Prober, limiter *Limiter) uint64 {
 	p := *prober
-	interval := *p.interval()
-	ticker := time.NewTicker(interval)
+	schedule := p.schedule()
+	params := p.params()
+	windows := params.Windows
+	overlap := params.Concurrency == CONCURRENCY_OVERLAP
+	longRunning := isLongRunning(params.Mode)
+
+	if params.MaxDuration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, params.MaxDuration)
+		defer cancel()
+	}
+
 	var counter atomic.Uint64
+	var inFlight sync.WaitGroup
+	slots := make(chan struct{}, params.MaxInFlight)
+
+	// stats must account for all the probes sent
+	finish := func() uint64 {
+		inFlight.Wait()
+		p.printStats()
+		return counter.Load()
+	}
+
+	inWindow := false
 	for {
+		planned := schedule.next(time.Now())
+
+		if windows != nil && !windows.active(planned) {
+			// stats are reported for every window once it closes
+			if inWindow {
+				inWindow = false
+				inFlight.Wait()
+				p.printStats()
+			}
+			opens, ok := windows.next(planned)
+			if !ok {
+				return finish()
+			}
+			planned = opens
+			schedule.reschedule()
+		} else {
+			inWindow = true
+		}
+
+		timer := time.NewTimer(time.Until(planned))
 		select {
-		case <-ticker.C:
-			delay := jitter.Delay(interval, 0.8787)
-			time.Sleep(delay)
+		case <-timer.C:
+			if !inWindow {
+				continue // the window just opened: probes are planned from now on
+			}
+			select {
+			case slots <- struct{}{}:
+			default:
+				// `max_in_flight` probes are still running
+				schedule.miss()
+				continue
+			}
+			release, err := limiter.acquire(ctx)
+			if err != nil {
+				// probing is over while waiting for the limiter
+				<-slots
+				return finish()
+			}
 			attempt := counter.Add(1)
-			p.probe(ctx, &attempt)
+			schedule.started(attempt, time.Now())
+			if overlap {
+				inFlight.Add(1)
+				go func(attempt uint64) {
+					defer inFlight.Done()
+					p.probe(ctx, &attempt)
+					release()
+					<-slots
+				}(attempt)
+			} else {
+				p.probe(ctx, &attempt)
+				release()
+				<-slots
+				if longRunning {
+					// discoveries span many slots on purpose: the next one is planned after this one ends
+					schedule.reschedule()
+				}
+			}
+			if params.MaxProbes > 0 && attempt >= params.MaxProbes {
+				return finish()
+			}
+		case <-p.tracerouteRequests():
+			timer.Stop()
+			p.traceroute(ctx, TRACEROUTE_TRIGGER_ON_DEMAND)
 		case <-ctx.Done():
-			ticker.Stop()
-			p.printStats()
-			return counter.Load()
+			timer.Stop()
+			return finish()
 		}
 	}
 }
 
-func NewProberFromRawURL(rawTaskURL *string) (prober *Prober, err error) {
-	defer err2.Handle(&err, "newProberTaskFromRawURL")
-
-	taskURL := try.To1(url.Parse(*rawTaskURL))
-	taskType := try.To1(getProberTaskType(taskURL))
-	taskIP := try.To1(getProberTaskIP(taskType, taskURL))
-	taskPort := try.To1(getProberTaskPort(taskURL))
-
-	taskTarget := netip.AddrPortFrom(taskIP, uint16(taskPort))
-
-	taskParams := newProberTaskParams(taskURL)
+func newProberTask(rawTaskURL string, taskURL *url.URL,
+	taskType ProberType, taskIP netip.Addr, taskPort uint16,
+	taskParams *proberTaskParams, taskProbePrinter *probePrinter,
+) *proberTask {
+	taskTarget := netip.AddrPortFrom(taskIP, taskPort)
 
-	taskStats := &proberTaskStats{
-		0, 0, 0, 0, 0, 0.0, 0.0, math.MaxFloat64, 0.0, math.MaxFloat64, 0.0, 0.0, 0.0, 0.0,
-	}
+	taskStats := &proberTaskStats{OverallMinLatency: math.MaxFloat64}
 
 	// max number of observations to keep for statistics
 	// |_ between 255 and 500 for low cpu/memory apps
-	latencies := ring.New(int(taskParams.LogSize))
+	latencies := newLatencyRing(int(taskParams.LogSize))
 
-	taskProbePrinter := newPrinter(taskURL, &taskParams.OutputFormat)
+	var taskFlows *proberTaskFlows
+	if taskParams.ECMPFlows > 0 {
+		taskFlows = newProberTaskFlows(taskParams.SourcePortRange)
+	}
+
+	var sequencer *probeSequencer
+	overlap := taskParams.Concurrency == CONCURRENCY_OVERLAP
+	if overlap {
+		sequencer = newProbeSequencer()
+	}
 
-	task := &proberTask{
-		Raw:       *rawTaskURL,
+	return &proberTask{
+		Raw:       rawTaskURL,
 		URL:       taskURL,
 		Type:      taskType,
 		IPv4:      isIPv4(taskType),
 		IPv6:      isIPv6(taskType),
 		IP:        taskIP,
-		Port:      uint16(taskPort),
+		Port:      taskPort,
 		Target:    &taskTarget,
 		Params:    taskParams,
 		Stats:     taskStats,
 		Latencies: latencies,
-		Printer:   &taskProbePrinter,
+		Sketch:    newProberTaskSketch(),
+		Rolling:   newProberTaskRolling(taskParams.StatsWindows),
+		Printer:   taskProbePrinter,
+		Flows:     taskFlows,
+		Schedule:  newProberSchedule(taskParams.Interval, taskParams.Jitter, startOffset(rawTaskURL, taskParams.Interval), overlap),
+
+		mutex:     &sync.Mutex{},
+		sequencer: sequencer,
+
+		tracing:            &atomic.Bool{},
+		onDemandTraceroute: make(chan struct{}, 1),
+	}
+}
+
+func newProberFromTask(task *proberTask) *Prober {
+	var p Prober
+	switch {
+	case task.Type == UNIX:
+		p = newUnixProberTask(task)
+	case task.Params.Mode == PROBE_MODE_PMTU:
+		p = newPMTUProberTask(task)
+	case task.Params.Mode == PROBE_MODE_PERSISTENT:
+		p = newPersistentTCPProberTask(task)
+	case task.Params.Mode == PROBE_MODE_IDLE:
+		p = newIdleTimeoutProberTask(task)
+	default:
+		p = newTCPProberTask(task)
+	}
+	return &p
+}
+
+func NewProberFromRawURL(rawTaskURL *string) (prober *Prober, err error) {
+	defer err2.Handle(&err, "newProberTaskFromRawURL")
+
+	taskURL := try.To1(url.Parse(*rawTaskURL))
+	taskType := try.To1(getProberTaskType(taskURL))
+	taskIP := try.To1(getProberTaskIP(taskType, taskURL))
+	taskPort := try.To1(getProberTaskPort(taskType, taskURL))
+
+	taskParams := try.To1(newProberTaskParams(taskURL))
+
+	taskProbePrinter := newPrinter(taskURL, &taskParams.OutputFormat)
+
+	task := newProberTask(*rawTaskURL, taskURL, taskType, taskIP, uint16(taskPort), taskParams, &taskProbePrinter)
+
+	return newProberFromTask(task), err
+}
+
+// NewProbersFromRawURL creates one prober per target described by the task URL:
+// a list of ports and ranges ( i/e: `:80,443,8000-8010` ) expands into one prober per port,
+// and so does a CIDR block or range of addresses ( i/e: `10.8.0.0/28` ) into one prober per address.
+func NewProbersFromRawURL(rawTaskURL *string) (probers []*Prober, err error) {
+	defer err2.Handle(&err, "NewProbersFromRawURL")
+
+	head, hostsSpec, portsSpec, tail := splitTaskURL(*rawTaskURL)
+	if portsSpec == "" {
+		prober := try.To1(NewProberFromRawURL(rawTaskURL))
+		return []*Prober{prober}, nil
+	}
+
+	// both the scheme and the params are known before the targets are
+	taskType := try.To1(getProberTaskType(try.To1(url.Parse(head))))
+	config := try.To1(url.Parse(tail)).Query()
+	maxTargets := try.To1(getMaxTargets(&config))
+
+	ports := try.To1(getProberTaskPorts(portsSpec))
+	addrs := try.To1(getProberTaskAddrs(taskType, hostsSpec, maxTargets))
+
+	hosts := []string{hostsSpec}
+	if addrs != nil {
+		hosts = make([]string, len(addrs))
+		for index, addr := range addrs {
+			hosts[index] = addr.String()
+			if addr.Is6() {
+				hosts[index] = "[" + hosts[index] + "]"
+			}
+		}
 	}
 
-	p := newTCPProberTask(task)
-	prober = &p
+	if targets := uint64(len(hosts)) * uint64(len(ports)); targets > uint64(maxTargets) {
+		return nil, errorx.WithMessagef(errorTooManyTargets, "%d > %d", targets, maxTargets)
+	}
+
+	var rawTaskURLs []string
+	var taskPorts []uint16
+	for _, host := range hosts {
+		for _, port := range ports {
+			rawTaskURLs = append(rawTaskURLs, head+host+":"+strconv.FormatUint(uint64(port), 10)+tail)
+			taskPorts = append(taskPorts, port)
+		}
+	}
+
+	if len(rawTaskURLs) == 1 {
+		prober := try.To1(NewProberFromRawURL(&rawTaskURLs[0]))
+		return []*Prober{prober}, nil
+	}
+
+	taskURLs := make([]*url.URL, len(rawTaskURLs))
+	targets := make([]string, len(rawTaskURLs))
+	for index, rawTaskURL := range rawTaskURLs {
+		taskURLs[index] = try.To1(url.Parse(rawTaskURL))
+		targets[index] = taskURLs[index].Host
+	}
+
+	// when only ports are expanded, all of them share the same IP which is resolved only once
+	taskURL := taskURLs[0]
+	taskIP := try.To1(getProberTaskIP(taskType, taskURL))
+	taskParams := try.To1(newProberTaskParams(taskURL))
+
+	// all targets share the same printer, which logs files named after all of them
+	printerURL := *taskURL
+	printerQuery := printerURL.Query()
+	if printerQuery.Get(PARAM_LOGZ_NAME) == "" {
+		printerQuery.Set(PARAM_LOGZ_NAME, stringFormatter.Format("{0}__{1}__{2}", taskURL.Scheme,
+			strings.NewReplacer(".", "_", "/", "_", ":", "_", "[", "", "]", "").Replace(hostsSpec),
+			strings.ReplaceAll(portsSpec, ",", "_")))
+		printerURL.RawQuery = printerQuery.Encode()
+	}
+	taskProbePrinter := newPrinter(&printerURL, &taskParams.OutputFormat)
+
+	group := newProberTaskGroup(strings.Trim(hostsSpec, "[]"), taskIP, targets)
+
+	for index, rawTaskURL := range rawTaskURLs {
+		IP := taskIP
+		if addrs != nil {
+			IP = addrs[index/len(ports)]
+		}
+		task := newProberTask(rawTaskURL, taskURLs[index],
+			taskType, IP, taskPorts[index], try.To1(newProberTaskParams(taskURLs[index])), &taskProbePrinter)
+		task.Group, task.groupMember = group, index
+		probers = append(probers, newProberFromTask(task))
+	}
 
-	return prober, err
+	return probers, nil
 }
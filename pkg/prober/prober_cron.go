@@ -0,0 +1,160 @@
+package prober
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/try"
+	errorx "github.com/pkg/errors"
+)
+
+type (
+	// cronField is the set of values a field matches: bit N is set when N matches
+	cronField uint64
+
+	// cronSpec is a standard 5 fields cron expression: `minute hour day-of-month month day-of-week`
+	cronSpec struct {
+		minute, hour, dom, month, dow cronField
+		// when both days are restricted, matching either of them is enough ( like `cron` does )
+		anyDay bool
+	}
+
+	// probeWindows are the periods when probing is active: each one opens when
+	// the cron expression matches, and closes `length` later.
+	probeWindows struct {
+		cron     *cronSpec
+		length   time.Duration
+		location *time.Location
+	}
+)
+
+var errorInvalidSchedule = errorx.New("invalid schedule")
+
+// max time to look ahead for the next window
+const maxWindowLookahead = 5 * 366 * 24 * time.Hour
+
+func parseCronField(field string, min, max int) (set cronField, restricted bool, err error) {
+	defer err2.Handle(&err, "parseCronField")
+
+	for _, item := range strings.Split(field, ",") {
+		valueRange, rawStep, hasStep := strings.Cut(item, "/")
+
+		step := 1
+		if hasStep {
+			step = try.To1(strconv.Atoi(rawStep))
+		}
+
+		first, last := min, max
+		if valueRange != "*" {
+			restricted = true
+			rawFirst, rawLast, isRange := strings.Cut(valueRange, "-")
+			first = try.To1(strconv.Atoi(rawFirst))
+			last = first
+			if isRange {
+				last = try.To1(strconv.Atoi(rawLast))
+			} else if hasStep {
+				last = max // `{first}/{step}`
+			}
+		}
+
+		if step <= 0 || first < min || last > max || first > last {
+			return 0, false, errorx.WithMessage(errorInvalidSchedule, field)
+		}
+
+		for value := first; value <= last; value += step {
+			set |= 1 << value
+		}
+	}
+	return set, restricted, nil
+}
+
+func parseCron(expression string) (cron *cronSpec, err error) {
+	defer err2.Handle(&err, "parseCron")
+
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return nil, errorx.WithMessagef(errorInvalidSchedule, "%s: expected 5 fields", expression)
+	}
+
+	cron = &cronSpec{}
+	var domRestricted, dowRestricted bool
+	cron.minute, _ = try.To2(parseCronField(fields[0], 0, 59))
+	cron.hour, _ = try.To2(parseCronField(fields[1], 0, 23))
+	cron.dom, domRestricted = try.To2(parseCronField(fields[2], 1, 31))
+	cron.month, _ = try.To2(parseCronField(fields[3], 1, 12))
+	cron.dow, dowRestricted = try.To2(parseCronField(fields[4], 0, 7))
+
+	// both 0 and 7 are Sunday
+	if cron.dow&(1<<7) != 0 {
+		cron.dow |= 1
+	}
+	cron.anyDay = domRestricted && dowRestricted
+
+	return cron, nil
+}
+
+func (f cronField) has(value int) bool {
+	return f&(1<<value) != 0
+}
+
+func (c *cronSpec) matchesDay(t time.Time) bool {
+	dom, dow := c.dom.has(t.Day()), c.dow.has(int(t.Weekday()))
+	if c.anyDay {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+// next provides the first minute at or after `t` matched by the expression
+func (c *cronSpec) next(from time.Time, limit time.Time) (time.Time, bool) {
+	t := from.Truncate(time.Minute)
+	if t.Before(from) {
+		t = t.Add(time.Minute)
+	}
+	for !t.After(limit) {
+		switch {
+		case !c.month.has(int(t.Month())):
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.matchesDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case !c.hour.has(t.Hour()):
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case !c.minute.has(t.Minute()):
+			t = t.Add(time.Minute)
+		default:
+			return t, true
+		}
+	}
+	return t, false
+}
+
+func newProbeWindows(expression string, length time.Duration, location *time.Location) (windows *probeWindows, err error) {
+	defer err2.Handle(&err, "newProbeWindows")
+
+	cron := try.To1(parseCron(expression))
+	if length <= 0 {
+		return nil, errorx.WithMessage(errorInvalidSchedule, "window length must be positive")
+	}
+	windows = &probeWindows{cron, length, location}
+	// i/e: `0 0 31 2 *` is valid, but no window would ever open
+	if _, ok := windows.next(time.Now()); !ok {
+		return nil, errorx.WithMessagef(errorInvalidSchedule, "%s: never matches within %s", expression, maxWindowLookahead)
+	}
+	return windows, nil
+}
+
+// active tells whether `t` falls within a window, which is the case
+// when the expression matched at most `length` before it.
+func (w *probeWindows) active(t time.Time) bool {
+	t = t.In(w.location)
+	opened, ok := w.cron.next(t.Add(-w.length).Add(time.Nanosecond), t)
+	return ok && !opened.After(t)
+}
+
+// next provides when the first window after `t` opens
+func (w *probeWindows) next(t time.Time) (time.Time, bool) {
+	t = t.In(w.location)
+	return w.cron.next(t, t.Add(maxWindowLookahead))
+}
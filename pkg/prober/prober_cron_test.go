@@ -0,0 +1,224 @@
+package prober
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func cronFieldOf(values ...int) cronField {
+	var field cronField
+	for _, value := range values {
+		field |= 1 << value
+	}
+	return field
+}
+
+func cronFieldRange(first, last, step int) cronField {
+	var field cronField
+	for value := first; value <= last; value += step {
+		field |= 1 << value
+	}
+	return field
+}
+
+func TestParseCronField(t *testing.T) {
+	tests := []struct {
+		field      string
+		min, max   int
+		set        cronField
+		restricted bool
+	}{
+		{"*", 0, 59, cronFieldRange(0, 59, 1), false},
+		{"*/15", 0, 59, cronFieldOf(0, 15, 30, 45), false},
+		{"5", 0, 59, cronFieldOf(5), true},
+		{"1-5", 0, 7, cronFieldRange(1, 5, 1), true},
+		{"10-20/5", 0, 59, cronFieldOf(10, 15, 20), true},
+		// `{first}/{step}` runs until the field's max
+		{"5/20", 0, 59, cronFieldOf(5, 25, 45), true},
+		{"1,3,5", 0, 7, cronFieldOf(1, 3, 5), true},
+		{"1-2,22-23", 0, 23, cronFieldOf(1, 2, 22, 23), true},
+		{"*/10", 1, 31, cronFieldOf(1, 11, 21, 31), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.field, func(t *testing.T) {
+			set, restricted, err := parseCronField(test.field, test.min, test.max)
+			if err != nil {
+				t.Fatalf("parseCronField() error = %v", err)
+			}
+			if set != test.set {
+				t.Errorf("parseCronField() = %b, want %b", set, test.set)
+			}
+			if restricted != test.restricted {
+				t.Errorf("parseCronField() restricted = %t, want %t", restricted, test.restricted)
+			}
+		})
+	}
+}
+
+func TestParseCronFieldRejectsInvalidFields(t *testing.T) {
+	for _, field := range []string{"", "60", "-1", "5-1", "*/0", "1/-1", "a", "1-", "1,,2", "0-60"} {
+		t.Run(field, func(t *testing.T) {
+			if _, _, err := parseCronField(field, 0, 59); err == nil {
+				t.Errorf("parseCronField(%q) error = nil", field)
+			}
+		})
+	}
+}
+
+func TestParseCron(t *testing.T) {
+	tests := []struct {
+		expression string
+		sunday     bool
+		anyDay     bool
+	}{
+		{"* * * * *", true, false},
+		{"0 9 * * 1-5", false, false},
+		// both 0 and 7 are Sunday
+		{"0 9 * * 0", true, false},
+		{"0 9 * * 7", true, false},
+		{"0 9 * * 5-7", true, false},
+		// only day-of-month is restricted: day-of-week matches any day
+		{"0 9 1 * *", true, false},
+		// both days are restricted: either of them is enough
+		{"0 9 1 * 1", false, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			cron, err := parseCron(test.expression)
+			if err != nil {
+				t.Fatalf("parseCron() error = %v", err)
+			}
+			if got := cron.dow.has(int(time.Sunday)); got != test.sunday {
+				t.Errorf("matches Sunday = %t, want %t", got, test.sunday)
+			}
+			if cron.anyDay != test.anyDay {
+				t.Errorf("anyDay = %t, want %t", cron.anyDay, test.anyDay)
+			}
+		})
+	}
+
+	for _, expression := range []string{"", "* * * *", "* * * * * *", "0 24 * * *", "0 0 0 * *", "0 0 * 13 *", "0 0 * * 8"} {
+		t.Run(expression, func(t *testing.T) {
+			if _, err := parseCron(expression); !errors.Is(err, errorInvalidSchedule) {
+				t.Errorf("parseCron(%q) error = %v, want %v", expression, err, errorInvalidSchedule)
+			}
+		})
+	}
+}
+
+func TestCronSpecNext(t *testing.T) {
+	// Monday
+	monday := time.Date(2024, 1, 1, 10, 7, 30, 0, time.UTC)
+
+	tests := []struct {
+		expression string
+		from       time.Time
+		want       time.Time
+	}{
+		{"*/15 * * * *", monday, time.Date(2024, 1, 1, 10, 15, 0, 0, time.UTC)},
+		// a match at `from` itself is the next one
+		{"*/15 * * * *", time.Date(2024, 1, 1, 10, 15, 0, 0, time.UTC), time.Date(2024, 1, 1, 10, 15, 0, 0, time.UTC)},
+		{"30 9 * * 1-5", time.Date(2024, 1, 6, 10, 0, 0, 0, time.UTC), time.Date(2024, 1, 8, 9, 30, 0, 0, time.UTC)},
+		{"0 12 * * 7", monday, time.Date(2024, 1, 7, 12, 0, 0, 0, time.UTC)},
+		{"0 0 13 * *", monday, time.Date(2024, 1, 13, 0, 0, 0, 0, time.UTC)},
+		// the 13th or any Friday: Friday the 5th comes first
+		{"0 0 13 * 5", monday, time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)},
+		// only the 13th: day-of-week is not restricted, so Fridays do not match on their own
+		{"0 0 13 * *", time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 9, 13, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 1 *", time.Date(2024, 12, 31, 23, 59, 0, 0, time.UTC), time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expression+"@"+test.from.Format(time.RFC3339), func(t *testing.T) {
+			cron, err := parseCron(test.expression)
+			if err != nil {
+				t.Fatalf("parseCron() error = %v", err)
+			}
+			got, ok := cron.next(test.from, test.from.Add(maxWindowLookahead))
+			if !ok || !got.Equal(test.want) {
+				t.Errorf("next() = %s, %t; want %s", got, ok, test.want)
+			}
+		})
+	}
+}
+
+func TestProbeWindows(t *testing.T) {
+	at := func(day, hour, minute, second int) time.Time {
+		return time.Date(2024, 1, day, hour, minute, second, 0, time.UTC)
+	}
+
+	// every day from 09:00 to 10:00
+	windows, err := newProbeWindows("0 9 * * *", time.Hour, time.UTC)
+	if err != nil {
+		t.Fatalf("newProbeWindows() error = %v", err)
+	}
+
+	activeTests := []struct {
+		at     time.Time
+		active bool
+	}{
+		{at(1, 8, 59, 59), false},
+		{at(1, 9, 0, 0), true},
+		{at(1, 9, 30, 0), true},
+		{at(1, 9, 59, 59), true},
+		{at(1, 10, 0, 0), false},
+		{at(1, 23, 0, 0), false},
+	}
+	for _, test := range activeTests {
+		if got := windows.active(test.at); got != test.active {
+			t.Errorf("active(%s) = %t, want %t", test.at.Format(time.TimeOnly), got, test.active)
+		}
+	}
+
+	nextTests := []struct {
+		at, opens time.Time
+	}{
+		{at(1, 8, 0, 0), at(1, 9, 0, 0)},
+		{at(1, 9, 0, 0), at(1, 9, 0, 0)},
+		{at(1, 9, 30, 0), at(2, 9, 0, 0)},
+		{at(1, 23, 0, 0), at(2, 9, 0, 0)},
+	}
+	for _, test := range nextTests {
+		if got, ok := windows.next(test.at); !ok || !got.Equal(test.opens) {
+			t.Errorf("next(%s) = %s, %t; want %s", test.at, got, ok, test.opens)
+		}
+	}
+
+	// windows follow the wall clock of their location: 09:00 at UTC-5 is 14:00 UTC
+	zoned, err := newProbeWindows("0 9 * * *", time.Hour, time.FixedZone("UTC-5", -5*60*60))
+	if err != nil {
+		t.Fatalf("newProbeWindows() error = %v", err)
+	}
+	if !zoned.active(at(1, 14, 30, 0)) || zoned.active(at(1, 9, 30, 0)) {
+		t.Errorf("active() does not honor the window's location")
+	}
+}
+
+func TestNewProbeWindowsRejectsInvalidWindows(t *testing.T) {
+	tests := []struct {
+		expression string
+		length     time.Duration
+	}{
+		{"0 9 * * *", 0},
+		{"0 9 * * *", -time.Minute},
+		// valid fields, but February never has 30 or 31 days
+		{"0 0 31 2 *", time.Hour},
+		{"0 0 30 2 *", time.Hour},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			if _, err := newProbeWindows(test.expression, test.length, time.UTC); !errors.Is(err, errorInvalidSchedule) {
+				t.Errorf("newProbeWindows() error = %v, want %v", err, errorInvalidSchedule)
+			}
+		})
+	}
+
+	if _, err := newProbeWindows("0 0 29 2 *", time.Hour, time.UTC); err != nil {
+		t.Errorf("newProbeWindows(leap day) error = %v", err)
+	}
+}
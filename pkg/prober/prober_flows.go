@@ -0,0 +1,156 @@
+package prober
+
+import (
+	"math"
+)
+
+type (
+	// stats for probes sharing the same 5-tuple: ECMP hashes them all into the same path
+	flowStats struct {
+		Port           uint16
+		TotalProbes    uint64
+		TotalFailures  uint64
+		Probes         uint64 // current window
+		Failures       uint64 // current window
+		MinLatency     float64
+		MaxLatency     float64
+		AverageLatency float64
+		LossRatio      float64
+		LatencyOutlier bool
+		LossOutlier    bool
+
+		latencySum     float64
+		latencySquares float64
+	}
+
+	proberTaskFlows struct {
+		firstPort uint16
+		flows     []*flowStats
+	}
+)
+
+const (
+	// a flow is flagged when its average latency deviates from the rest of the flows' average
+	// by more than `flowLatencySigmas` standard errors and by at least `flowLatencyMinDelta`
+	flowLatencySigmas   = 3.0
+	flowLatencyMinDelta = 0.1 // ratio of the rest of the flows' average latency
+	// a flow is flagged when its loss ratio exceeds the rest of the flows' by this much
+	flowLossMinDelta = 0.05
+	// flows with fewer probes in the window are not flagged
+	flowMinProbes = 5
+)
+
+func newProberTaskFlows(ports *portRange) *proberTaskFlows {
+	flows := make([]*flowStats, ports.size())
+	for index := range flows {
+		flows[index] = &flowStats{Port: ports.port(uint64(index))}
+		flows[index].reset()
+	}
+	return &proberTaskFlows{firstPort: ports.first, flows: flows}
+}
+
+func (f *flowStats) reset() {
+	f.Probes = 0
+	f.Failures = 0
+	f.MinLatency = math.MaxFloat64
+	f.MaxLatency = 0.0
+	f.latencySum = 0.0
+	f.latencySquares = 0.0
+}
+
+func (f *flowStats) successful() uint64 {
+	return f.Probes - f.Failures
+}
+
+func (f *proberTaskFlows) flow(port uint16) *flowStats {
+	index := int(port) - int(f.firstPort)
+	if index < 0 || index >= len(f.flows) {
+		return nil
+	}
+	return f.flows[index]
+}
+
+func (f *proberTaskFlows) observe(port uint16, rtt float64, err error) {
+	flow := f.flow(port)
+	if flow == nil {
+		return
+	}
+
+	flow.TotalProbes += 1
+	flow.Probes += 1
+
+	if err != nil {
+		flow.TotalFailures += 1
+		flow.Failures += 1
+		return
+	}
+
+	// only successful probes contribute to latency: failures are accounted as loss
+	flow.latencySum += rtt
+	flow.latencySquares += rtt * rtt
+	if rtt > flow.MaxLatency {
+		flow.MaxLatency = rtt
+	}
+	if rtt < flow.MinLatency {
+		flow.MinLatency = rtt
+	}
+}
+
+// evaluate computes the window's stats for all flows, and flags those
+// that behave significantly different from the rest: a single bad ECMP path.
+func (f *proberTaskFlows) evaluate() {
+	for _, flow := range f.flows {
+		flow.AverageLatency = 0.0
+		flow.LossRatio = 0.0
+		if successful := flow.successful(); successful > 0 {
+			flow.AverageLatency = flow.latencySum / float64(successful)
+		}
+		if flow.Probes > 0 {
+			flow.LossRatio = float64(flow.Failures) / float64(flow.Probes)
+		}
+	}
+
+	// all flows are pooled together once: each flow's baseline is the pool without it
+	var totalProbes, totalFailures, totalSuccessful uint64
+	var totalLatencySum, totalLatencySquares float64
+	for _, flow := range f.flows {
+		totalProbes += flow.Probes
+		totalFailures += flow.Failures
+		totalSuccessful += flow.successful()
+		totalLatencySum += flow.latencySum
+		totalLatencySquares += flow.latencySquares
+	}
+
+	for _, flow := range f.flows {
+		flow.LatencyOutlier = false
+		flow.LossOutlier = false
+
+		if flow.Probes < flowMinProbes {
+			continue
+		}
+
+		probes := totalProbes - flow.Probes
+		failures := totalFailures - flow.Failures
+		successful := totalSuccessful - flow.successful()
+		latencySum := totalLatencySum - flow.latencySum
+		latencySquares := totalLatencySquares - flow.latencySquares
+
+		if flow.successful() > 0 && successful > 1 {
+			mean := latencySum / float64(successful)
+			variance := math.Max(latencySquares/float64(successful)-mean*mean, 0.0)
+			standardError := math.Sqrt(variance / float64(flow.successful()))
+			delta := math.Abs(flow.AverageLatency - mean)
+			flow.LatencyOutlier = delta > flowLatencySigmas*standardError && delta > flowLatencyMinDelta*mean
+		}
+
+		if probes > 0 {
+			flow.LossOutlier = flow.LossRatio-float64(failures)/float64(probes) > flowLossMinDelta
+		}
+	}
+}
+
+func (f *proberTaskFlows) reset() {
+	for _, flow := range f.flows {
+		flow.reset()
+	}
+}
@@ -0,0 +1,116 @@
+package prober
+
+import (
+	"context"
+	"math"
+	"net/netip"
+	"sync"
+	"time"
+)
+
+type (
+	// stats for one of the targets a task URL expands into
+	groupMemberStats struct {
+		Target         string
+		Up             bool // whether the last probe succeeded
+		LastError      string
+		TotalProbes    uint64
+		TotalFailures  uint64
+		Probes         uint64 // current window
+		Failures       uint64 // current window
+		MinLatency     float64
+		MaxLatency     float64
+		AverageLatency float64
+
+		latencySum float64
+	}
+
+	// proberTaskGroup ties together the probers created from a single task URL:
+	// they share the hostname resolution, and are summarized together.
+	proberTaskGroup struct {
+		mutex      sync.Mutex
+		Host       string
+		IP         netip.Addr
+		Members    []*groupMemberStats
+		resolvedAt uint64
+	}
+)
+
+func newProberTaskGroup(host string, IP netip.Addr, targets []string) *proberTaskGroup {
+	members := make([]*groupMemberStats, len(targets))
+	for index, target := range targets {
+		members[index] = &groupMemberStats{Target: target}
+		members[index].reset()
+	}
+	return &proberTaskGroup{Host: host, IP: IP, Members: members}
+}
+
+func (m *groupMemberStats) reset() {
+	m.Probes = 0
+	m.Failures = 0
+	m.MinLatency = math.MaxFloat64
+	m.MaxLatency = 0.0
+	m.AverageLatency = 0.0
+	m.latencySum = 0.0
+}
+
+func (m *groupMemberStats) successful() uint64 {
+	return m.Probes - m.Failures
+}
+
+// resolve refreshes the group's IP once per DNS interval: the first member to reach
+// the attempt resolves the hostname, the others just pick up the result.
+func (g *proberTaskGroup) resolve(ctx context.Context, pt *proberTask, attempt uint64) (netip.Addr, time.Duration, bool, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if attempt <= g.resolvedAt {
+		return g.IP, 0, false, nil
+	}
+	g.resolvedAt = attempt
+
+	IP, latency, err := pt.resolveHostname(ctx)
+	if err == nil {
+		g.IP = IP
+	}
+	return IP, latency, true, err
+}
+
+func (g *proberTaskGroup) observe(member int, rtt float64, err error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	stats := g.Members[member]
+	stats.TotalProbes += 1
+	stats.Probes += 1
+	stats.Up = err == nil
+
+	if err != nil {
+		stats.TotalFailures += 1
+		stats.Failures += 1
+		stats.LastError = err.Error()
+		return
+	}
+
+	stats.LastError = ""
+	stats.latencySum += rtt
+	stats.AverageLatency = stats.latencySum / float64(stats.successful())
+	if rtt > stats.MaxLatency {
+		stats.MaxLatency = rtt
+	}
+	if rtt < stats.MinLatency {
+		stats.MinLatency = rtt
+	}
+}
+
+// printStats reports all members' current window, and starts a new one
+func (g *proberTaskGroup) printStats(pt *proberTask) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	(*pt.Printer).printGroupStats(pt, g)
+
+	for _, member := range g.Members {
+		member.reset()
+	}
+}
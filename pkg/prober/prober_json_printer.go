@@ -5,8 +5,8 @@ import (
 	"net/netip"
 	"net/url"
 	"os"
-	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -27,6 +27,7 @@ type (
 		logFilesCounter                    *atomic.Uint64
 		logRotateSecs                      time.Duration
 		writer                             io.Writer
+		closeOnce                          *sync.Once
 	}
 )
 
@@ -37,6 +38,7 @@ func newJSONProbePrinter(guid, logName string, url *url.URL) probePrinter {
 		guid:            &guid,
 		logName:         &logName,
 		logFilesCounter: &logFilesCounter,
+		closeOnce:       &sync.Once{},
 	}
 
 	params := url.Query()
@@ -52,23 +54,20 @@ func newJSONProbePrinter(guid, logName string, url *url.URL) probePrinter {
 	printer.logDir = &logDir
 
 	// `name` to be used as part of the log file name pattern `ping_{log_file_number}__{log_name}.json`
-	if logFileName == "" {
+	if logFileName == "" && url.Scheme == UNIX_SCHEME {
+		// unix sockets have no hostname nor port, default to: `{scheme}__{path}`
+		logFileName = stringFormatter.Format("{0}__{1}", url.Scheme, strings.ReplaceAll(strings.Trim(url.Path, "/"), "/", "_"))
+	} else if logFileName == "" {
 		// if no `log_name` is provided, default to: `{scheme}__{hostname}__{port}`
 		logFileName = stringFormatter.Format("{0}__{1}__{2}", url.Scheme, strings.ReplaceAll(url.Hostname(), ".", "_"), url.Port())
 	}
 	printer.logFileName = &logFileName
 
 	// rotation will happen on a time basis; default is 10m
-	printer.logRotateSecs = JSON_LOG_ROTATE_SECS * time.Second
-	logRotateSecs := params.Get(PARAM_LOGZ_ROTATE_SECS)
-	if rotateSecs, err := strconv.Atoi(logRotateSecs); err == nil {
-		printer.logRotateSecs = time.Duration(rotateSecs) * time.Second
-	}
+	// invalid values were already rejected by `newProberTaskParams`
+	printer.logRotateSecs, _ = getLogRotate(&params)
 
-	isFileLoggerSync := true
-	if paramLogzSync, err := strconv.ParseBool(params.Get(PARAM_LOGZ_SYNC)); err == nil {
-		isFileLoggerSync = paramLogzSync
-	}
+	isFileLoggerSync, _ := getLogSync(&params)
 
 	if writer, err := logrotate.New(logrotateLogger, logrotate.Options{
 		Directory:            *printer.logDir,
@@ -88,6 +87,18 @@ func newJSONProbePrinter(guid, logName string, url *url.URL) probePrinter {
 	return printer
 }
 
+// close waits for all queued records to be written into the log file; stdout is left open
+func (p *jsonProbePrinter) close() (err error) {
+	closer, ok := p.writer.(io.Closer)
+	if !ok || p.writer == os.Stdout {
+		return nil
+	}
+	p.closeOnce.Do(func() {
+		err = closer.Close()
+	})
+	return err
+}
+
 func (p *jsonProbePrinter) logFileNameProvider() string {
 	newLogFileName := stringFormatter.Format("ping_{0}__{1}.json", p.logFilesCounter.Add(1), *p.logFileName)
 	logrotateLogger.Printf("created new log file: '%s/%s'\n", *p.logDir, newLogFileName)
@@ -98,37 +109,151 @@ func (p *jsonProbePrinter) newJSON(task *proberTask) *gabs.Container {
 	json := gabs.New()
 	json.Set(p.guid, "id")
 	json.Set(p.logName, "logName")
-	json.Set(task.URL.Host, "host")
+	json.Set(task.host(), "host")
 	return json
 }
 
+// setTrafficFields echoes the socket options applied to probes, so results can be grouped by traffic class
+func (p *jsonProbePrinter) setTrafficFields(task *proberTask, json *gabs.Container) {
+	params := task.Params
+	if params.TOS != nil && !task.IPv6 {
+		json.Set(*params.TOS, "socket", "tos")
+		json.Set(*params.TOS>>2, "socket", "dscp")
+	}
+	if params.TrafficClass != nil && !task.IPv4 {
+		json.Set(*params.TrafficClass, "socket", "tclass")
+		json.Set(*params.TrafficClass>>2, "socket", "dscp")
+	}
+	if params.Mark != nil {
+		json.Set(*params.Mark, "socket", "mark")
+	}
+	if params.Priority != nil {
+		json.Set(*params.Priority, "socket", "priority")
+	}
+}
+
 func (p *jsonProbePrinter) printProbe(task *proberTask,
 	attempt *uint64, target *netip.AddrPort,
-	latency *time.Duration, err error,
+	data *proberTaskData, err error,
 ) {
 	json := p.newJSON(task)
 
+	latency := data.latency
+
 	if err != nil {
 		json.Set("ERROR", "severity")
 		json.Set(err.Error(), "error")
 	}
 
+	address := task.address(target)
+
 	json.Set(*attempt, "serial")
-	json.Set(target.String(), "target")
-	json.Set(task.Stats.LastLatency, "latency")
-	json.Set(task.Stats.DeltaLatency, "delta")
+	json.Set(address, "target")
+	json.Set(data.rtt, "latency")
+	json.Set(data.delta, "delta")
+
+	if data.localAddr != "" {
+		json.Set(data.localAddr, "local")
+	}
+
+	if data.connection > 0 {
+		json.Set(data.connection, "connection", "serial")
+	}
+
+	if timing := data.timing; timing != nil && !timing.Started.IsZero() {
+		delay := timing.Started.Sub(timing.Planned)
+		json.Set(timing.Planned.Format(time.RFC3339Nano), "schedule", "planned")
+		json.Set(timing.Started.Format(time.RFC3339Nano), "schedule", "started")
+		json.Set(asMillis(&delay), "schedule", "delay")
+		if timing.Skipped > 0 {
+			json.Set(timing.Skipped, "schedule", "skipped")
+		}
+	}
+
+	p.setTrafficFields(task, json)
+
+	if info := data.tcpInfo; info != nil {
+		json.Set(info.RTT, "tcp_info", "rtt")
+		json.Set(info.RTTVariance, "tcp_info", "rttvar")
+		json.Set(info.SYNRetransmits, "tcp_info", "syn_retransmits")
+		json.Set(info.MSS, "tcp_info", "mss")
+		json.Set(info.CongestionWindow, "tcp_info", "cwnd")
+	}
+
+	if loss := data.synLoss; loss != nil {
+		json.Set(true, "syn_loss", "recovered")
+		json.Set(loss.Retransmits, "syn_loss", "retransmits")
+		json.Set(loss.Confirmed, "syn_loss", "confirmed")
+		if err == nil {
+			json.Set("WARNING", "severity")
+		}
+	}
+
+	if data.closeLatency != nil {
+		json.Set(task.Params.CloseMode, "close", "mode")
+		json.Set(asMillis(data.closeLatency), "close", "latency")
+	}
+	if data.closeErr != nil {
+		json.Set(data.closeErr.Error(), "close", "error")
+		if err == nil {
+			json.Set("WARNING", "severity")
+		}
+	}
 
 	var message string
-	if task.Type == RAW_IPv4 || task.Type == RAW_IPv6 {
-		message = stringFormatter.Format("#:{0} | @:{1} | latency:{2}", *attempt, target.String(), *latency)
+	if task.Type == RAW_IPv4 || task.Type == RAW_IPv6 || task.Type == UNIX {
+		message = stringFormatter.Format("#:{0} | @:{1} | latency:{2}", *attempt, address, *latency)
 	} else {
-		message = stringFormatter.Format("#:{0} | @:{1}/{2} | latency:{3}", *attempt, task.URL.Hostname(), target.String(), *latency)
+		message = stringFormatter.Format("#:{0} | @:{1}/{2} | latency:{3}", *attempt, task.URL.Hostname(), address, *latency)
 	}
 	json.Set(message, "message")
 
 	io.WriteString(p.writer, json.String()+"\n")
 }
 
+// setQuantiles reports the configured latency quantiles for the current window and for the task's lifetime
+func (p *jsonProbePrinter) setQuantiles(task *proberTask, json *gabs.Container) {
+	for _, quantile := range task.Params.Quantiles {
+		name := quantileName(quantile)
+		if value, ok := task.Sketch.Window.quantile(quantile); ok {
+			json.Set(value, "latency", name)
+		}
+		if value, ok := task.Sketch.Overall.quantile(quantile); ok {
+			json.Set(value, "latency", "overall", name)
+		}
+	}
+}
+
+// windowName renders `window` the way it is usually written, i/e: `1h` instead of `1h0m0s`
+func windowName(window time.Duration) string {
+	name := window.String()
+	if strings.HasSuffix(name, "m0s") {
+		name = strings.TrimSuffix(name, "0s")
+	}
+	if strings.HasSuffix(name, "h0m") {
+		name = strings.TrimSuffix(name, "0m")
+	}
+	return name
+}
+
+func (p *jsonProbePrinter) setRollingStats(windows []*rollingStats, json *gabs.Container) {
+	for _, window := range windows {
+		name := windowName(window.Window)
+		json.Set(window.Probes, "windows", name, "count", "total")
+		json.Set(window.Failures, "windows", name, "count", "ko")
+		if window.Probes == 0 {
+			continue
+		}
+		json.Set(window.Availability, "windows", name, "availability")
+		if window.Probes > window.Failures {
+			json.Set(window.MinLatency, "windows", name, "latency", "min")
+			json.Set(window.MaxLatency, "windows", name, "latency", "max")
+			json.Set(window.AverageLatency, "windows", name, "latency", "avg")
+			json.Set(window.StandardDeviation, "windows", name, "latency", "sigma")
+		}
+	}
+}
+
 func (p *jsonProbePrinter) printStats(task *proberTask, probesCount *logSizeType) {
 	json := p.newJSON(task)
 
@@ -139,22 +264,184 @@ func (p *jsonProbePrinter) printStats(task *proberTask, probesCount *logSizeType
 	json.Set(stats.TotalFailures, "count", "ko")
 	json.Set(stats.ConsecutiveSuccesful, "count", "consecutive", "ok")
 	json.Set(stats.ConsecutiveFailures, "count", "consecutive", "ko")
+	json.Set(stats.SYNLossRecovered, "count", "syn_loss")
+	json.Set(stats.MissedProbes, "count", "missed")
+
+	// discoveries ( `pmtu` and `idle_timeout` probes ) observe no latencies
+	if *probesCount > 0 {
+		json.Set(stats.OverallMinLatency, "latency", "overall", "min")
+		json.Set(stats.Window.Min, "latency", "min")
+		json.Set(stats.OverallMaxLatency, "latency", "overall", "max")
+		json.Set(stats.Window.Max, "latency", "max")
+
+		json.Set(stats.Window.Average, "latency", "avg")
+		json.Set(stats.Window.StandardDeviation, "latency", "sigma")
+		json.Set(stats.Window.Skewness, "latency", "skew")
+	}
+
+	if successful := stats.Successful; successful.Count > 0 {
+		json.Set(successful.Count, "latency", "successful", "count")
+		json.Set(successful.Min, "latency", "successful", "min")
+		json.Set(successful.Max, "latency", "successful", "max")
+		json.Set(successful.Average, "latency", "successful", "avg")
+		json.Set(successful.StandardDeviation, "latency", "successful", "sigma")
+		json.Set(successful.Skewness, "latency", "successful", "skew")
+	}
 
-	json.Set(stats.OverallMinLatency, "latency", "overall", "min")
-	json.Set(stats.MinLatency, "latency", "min")
-	json.Set(stats.OverallMaxLatency, "latency", "overall", "max")
-	json.Set(stats.MaxLatency, "latency", "max")
+	p.setQuantiles(task, json)
 
-	json.Set(stats.AverageLatency, "latency", "avg")
-	json.Set(stats.StandardDeviation, "latency", "sigma")
-	json.Set(stats.Skewness, "latency", "skew")
+	p.setRollingStats(stats.Rolling, json)
+
+	outliers := 0
+	if task.Flows != nil {
+		outliers = p.setFlows(task.Flows, json)
+	}
 
 	message := stringFormatter.Format("{0} | [last {1}]: min/max/avg/sigma/skew={2}/{3}/{4}/{5}/{6} | [total: {7}]: min/max={8}/{9}",
-		task.URL.Host, *probesCount,
-		stats.MinLatency, stats.MaxLatency,
-		stats.AverageLatency, stats.StandardDeviation, stats.Skewness,
+		task.host(), *probesCount,
+		stats.Window.Min, stats.Window.Max,
+		stats.Window.Average, stats.Window.StandardDeviation, stats.Window.Skewness,
 		stats.TotalProbes, stats.OverallMinLatency, stats.OverallMaxLatency)
+	if *probesCount == 0 {
+		message = stringFormatter.Format("{0} | [total: {1}]: ok/ko={2}/{3}",
+			task.host(), stats.TotalProbes, stats.TotalSuccessful, stats.TotalFailures)
+	}
+
+	if outliers > 0 {
+		json.Set("WARNING", "severity")
+		message += stringFormatter.Format(" | [flows]: {0}/{1} outliers", outliers, len(task.Flows.flows))
+	}
+
+	json.Set(message, "message")
+
+	io.WriteString(p.writer, json.String()+"\n")
+}
+
+// setFlows reports per-flow stats for the current window, and how many flows were flagged
+func (p *jsonProbePrinter) setFlows(flows *proberTaskFlows, json *gabs.Container) int {
+	outliers := 0
+	json.Array("flows")
+	for _, flow := range flows.flows {
+		flowJSON := gabs.New()
+		flowJSON.Set(flow.Port, "port")
+		flowJSON.Set(flow.Probes, "count", "total")
+		flowJSON.Set(flow.Failures, "count", "ko")
+		flowJSON.Set(flow.TotalProbes, "count", "overall", "total")
+		flowJSON.Set(flow.TotalFailures, "count", "overall", "ko")
+		flowJSON.Set(flow.LossRatio, "loss")
+		if flow.successful() > 0 {
+			flowJSON.Set(flow.MinLatency, "latency", "min")
+			flowJSON.Set(flow.MaxLatency, "latency", "max")
+			flowJSON.Set(flow.AverageLatency, "latency", "avg")
+		}
+		flowJSON.Set(flow.LatencyOutlier, "outlier", "latency")
+		flowJSON.Set(flow.LossOutlier, "outlier", "loss")
+		if flow.LatencyOutlier || flow.LossOutlier {
+			outliers += 1
+		}
+		json.ArrayAppend(flowJSON.Data(), "flows")
+	}
+	return outliers
+}
 
+// printGroupStats summarizes all targets a task URL expands into, i/e: all ports of a host
+func (p *jsonProbePrinter) printGroupStats(task *proberTask, group *proberTaskGroup) {
+	json := p.newJSON(task)
+
+	json.Set(group.Host, "host")
+	json.Array("targets")
+
+	var down []string
+	for _, member := range group.Members {
+		memberJSON := gabs.New()
+		memberJSON.Set(member.Target, "target")
+		memberJSON.Set(member.Up, "up")
+		memberJSON.Set(member.Probes, "count", "total")
+		memberJSON.Set(member.Failures, "count", "ko")
+		memberJSON.Set(member.TotalProbes, "count", "overall", "total")
+		memberJSON.Set(member.TotalFailures, "count", "overall", "ko")
+		if member.successful() > 0 {
+			memberJSON.Set(member.MinLatency, "latency", "min")
+			memberJSON.Set(member.MaxLatency, "latency", "max")
+			memberJSON.Set(member.AverageLatency, "latency", "avg")
+		}
+		if member.LastError != "" {
+			memberJSON.Set(member.LastError, "error")
+		}
+		json.ArrayAppend(memberJSON.Data(), "targets")
+
+		if !member.Up && member.TotalProbes > 0 {
+			down = append(down, member.Target)
+		}
+	}
+
+	up := len(group.Members) - len(down)
+	json.Set(up, "count", "up")
+	json.Set(len(down), "count", "down")
+
+	if len(down) > 0 {
+		json.Set("WARNING", "severity")
+	}
+
+	message := stringFormatter.Format("{0} | [targets]: up {1}/{2}", group.Host, up, len(group.Members))
+	if len(down) > 0 {
+		message += stringFormatter.Format(" | down: {0}", strings.Join(down, " "))
+	}
+	json.Set(message, "message")
+
+	io.WriteString(p.writer, json.String()+"\n")
+}
+
+func (p *jsonProbePrinter) printVerdict(task *proberTask, verdict *proberVerdict) {
+	json := p.newJSON(task)
+
+	json.Set(verdict.Passed, "verdict", "passed")
+	json.Set(task.Stats.TotalProbes, "verdict", "probes")
+	json.Array("verdict", "criteria")
+
+	outcome := "PASSED"
+	if !verdict.Passed {
+		outcome = "FAILED"
+		json.Set("ERROR", "severity")
+	}
+
+	results := make([]string, len(verdict.Criteria))
+	for index, criterion := range verdict.Criteria {
+		criterionJSON := gabs.New()
+		criterionJSON.Set(criterion.Name, "name")
+		criterionJSON.Set(criterion.Threshold, "threshold")
+		criterionJSON.Set(criterion.Value, "value")
+		criterionJSON.Set(criterion.Passed, "passed")
+		json.ArrayAppend(criterionJSON.Data(), "verdict", "criteria")
+
+		results[index] = stringFormatter.Format("{0}={1} ( {2}: {3} )",
+			criterion.Name, criterion.Value, criterion.Threshold, criterion.Passed)
+	}
+
+	message := stringFormatter.Format("{0} | verdict: {1} | {2}", task.host(), outcome, strings.Join(results, " | "))
+	json.Set(message, "message")
+
+	io.WriteString(p.writer, json.String()+"\n")
+}
+
+func (p *jsonProbePrinter) printIntervalChange(task *proberTask, attempt *uint64, change *intervalChange) {
+	json := p.newJSON(task)
+
+	json.Set(*attempt, "serial")
+	json.Set(change.Failing, "interval", "failing")
+	json.Set(asMillis(&change.From), "interval", "from")
+	json.Set(asMillis(&change.To), "interval", "to")
+	json.Set(change.Consecutive, "interval", "consecutive")
+
+	var message string
+	if change.Failing {
+		json.Set("WARNING", "severity")
+		message = stringFormatter.Format("#:{0} | {1} | probing every {2} ( was {3} ) after {4} consecutive failures",
+			*attempt, task.host(), change.To, change.From, change.Consecutive)
+	} else {
+		message = stringFormatter.Format("#:{0} | {1} | probing every {2} ( was {3} ) after {4} consecutive successes",
+			*attempt, task.host(), change.To, change.From, change.Consecutive)
+	}
 	json.Set(message, "message")
 
 	io.WriteString(p.writer, json.String()+"\n")
@@ -193,3 +480,203 @@ func (p *jsonProbePrinter) printDNSUpdate(
 
 	io.WriteString(p.writer, json.String()+"\n")
 }
+
+func (p *jsonProbePrinter) printTraceroute(
+	task *proberTask,
+	target *netip.AddrPort,
+	hops []*tracerouteHop,
+	trigger string,
+) {
+	json := p.newJSON(task)
+
+	json.Set(target.String(), "target")
+	json.Set(trigger, "traceroute", "trigger")
+	json.Array("traceroute", "hops")
+
+	reached := false
+	path := make([]string, len(hops))
+	for index, hop := range hops {
+		hopJSON := gabs.New()
+		hopJSON.Set(hop.TTL, "ttl")
+		hopJSON.Set(asMillis(&hop.Latency), "latency")
+		hopJSON.Set(hop.Reached, "reached")
+
+		address := "*"
+		if hop.Address != nil {
+			address = hop.Address.String()
+			hopJSON.Set(address, "address")
+		}
+		if hop.ICMPType != 0 {
+			hopJSON.Set(hop.ICMPType, "icmp", "type")
+			hopJSON.Set(hop.ICMPCode, "icmp", "code")
+		}
+		if hop.Err != nil {
+			hopJSON.Set(hop.Err.Error(), "error")
+		}
+		json.ArrayAppend(hopJSON.Data(), "traceroute", "hops")
+
+		path[index] = stringFormatter.Format("{0}:{1}", hop.TTL, address)
+		reached = reached || hop.Reached
+	}
+
+	json.Set(reached, "traceroute", "reached")
+	if !reached {
+		json.Set("WARNING", "severity")
+	}
+
+	message := stringFormatter.Format("traceroute to {0} ( {1} ) | hops:{2} | reached:{3} | {4}",
+		target.String(), trigger, len(hops), reached, strings.Join(path, " "))
+	json.Set(message, "message")
+
+	io.WriteString(p.writer, json.String()+"\n")
+}
+
+func (p *jsonProbePrinter) printPMTU(
+	task *proberTask,
+	attempt *uint64,
+	target *netip.AddrPort,
+	discovery *pmtuDiscovery,
+	err error,
+) {
+	json := p.newJSON(task)
+
+	json.Set(*attempt, "serial")
+	json.Set(target.String(), "target")
+
+	json.Set(discovery.MTU, "pmtu", "mtu")
+	json.Set(discovery.RouteMTU, "pmtu", "route")
+	json.Set(discovery.PreviousMTU, "pmtu", "previous")
+	json.Set(discovery.Changed, "pmtu", "changed")
+	json.Set(discovery.BlackHole, "pmtu", "black_hole")
+	json.Set(discovery.Probes, "pmtu", "probes")
+
+	var message string
+	if err != nil {
+		json.Set("ERROR", "severity")
+		json.Set(err.Error(), "error")
+		message = stringFormatter.Format("#:{0} | @:{1} | path MTU discovery failed: {2}", *attempt, target.String(), err.Error())
+	} else {
+		if discovery.Changed || discovery.BlackHole {
+			json.Set("WARNING", "severity")
+		}
+		message = stringFormatter.Format("#:{0} | @:{1} | pmtu:{2} | route:{3} | previous:{4} | black_hole:{5}",
+			*attempt, target.String(), discovery.MTU, discovery.RouteMTU, discovery.PreviousMTU, discovery.BlackHole)
+	}
+	json.Set(message, "message")
+
+	io.WriteString(p.writer, json.String()+"\n")
+}
+
+func (p *jsonProbePrinter) printConnectionEvent(
+	task *proberTask,
+	target *netip.AddrPort,
+	event *connectionEvent,
+) {
+	json := p.newJSON(task)
+
+	json.Set("WARNING", "severity")
+	json.Set(target.String(), "target")
+
+	json.Set(event.Serial, "connection", "serial")
+	json.Set(event.LocalAddr, "connection", "local")
+	json.Set(event.Cause, "connection", "cause")
+	json.Set(asMillis(&event.Age), "connection", "age")
+	json.Set(event.Checks, "connection", "checks")
+	if event.Err != nil {
+		json.Set(event.Err.Error(), "error")
+	}
+
+	message := stringFormatter.Format("connection #{0} to {1} from {2} died after {3} ( {4} checks ): {5}",
+		event.Serial, target.String(), event.LocalAddr, event.Age, event.Checks, event.Cause)
+	json.Set(message, "message")
+
+	io.WriteString(p.writer, json.String()+"\n")
+}
+
+func (p *jsonProbePrinter) printIdleTimeout(
+	task *proberTask,
+	attempt *uint64,
+	target *netip.AddrPort,
+	discovery *idleTimeoutDiscovery,
+	err error,
+) {
+	json := p.newJSON(task)
+
+	json.Set(*attempt, "serial")
+	json.Set(target.String(), "target")
+
+	json.Set(asMillis(&discovery.Estimate), "idle_timeout", "estimate")
+	json.Set(asMillis(&discovery.LowerBound), "idle_timeout", "lower_bound")
+	json.Set(asMillis(&discovery.UpperBound), "idle_timeout", "upper_bound")
+	json.Set(discovery.Bounded, "idle_timeout", "bounded")
+	json.Set(discovery.Rounds, "idle_timeout", "rounds")
+	json.Array("idle_timeout", "tests")
+
+	for _, test := range discovery.Tests {
+		testJSON := gabs.New()
+		testJSON.Set(asMillis(&test.Idle), "idle")
+		testJSON.Set(test.Alive, "alive")
+		if !test.Alive {
+			testJSON.Set(test.Cause, "cause")
+		}
+		if test.Err != nil {
+			testJSON.Set(test.Err.Error(), "error")
+		}
+		json.ArrayAppend(testJSON.Data(), "idle_timeout", "tests")
+	}
+
+	var message string
+	switch {
+	case err != nil:
+		json.Set("ERROR", "severity")
+		json.Set(err.Error(), "error")
+		message = stringFormatter.Format("#:{0} | @:{1} | idle timeout discovery failed: {2}", *attempt, target.String(), err.Error())
+	case discovery.Bounded:
+		message = stringFormatter.Format("#:{0} | @:{1} | idle timeout:~{2} [ {3} , {4} ]",
+			*attempt, target.String(), discovery.Estimate, discovery.LowerBound, discovery.UpperBound)
+	default:
+		message = stringFormatter.Format("#:{0} | @:{1} | idle timeout: > {2}", *attempt, target.String(), discovery.UpperBound)
+	}
+	json.Set(message, "message")
+
+	io.WriteString(p.writer, json.String()+"\n")
+}
+
+func (p *jsonProbePrinter) printBurst(
+	task *proberTask,
+	attempt *uint64,
+	target *netip.AddrPort,
+	result *burstResult,
+) {
+	json := p.newJSON(task)
+
+	json.Set(*attempt, "serial")
+	json.Set(target.String(), "target")
+
+	json.Set(result.Size, "burst", "size")
+	json.Set(result.Successful, "burst", "ok")
+	json.Set(result.Failed, "burst", "ko")
+	json.Set(float64(result.Successful)/float64(result.Size), "burst", "ratio")
+
+	json.Set(result.MinLatency, "burst", "latency", "min")
+	json.Set(result.MaxLatency, "burst", "latency", "max")
+	json.Set(result.AvgLatency, "burst", "latency", "avg")
+	json.Set(result.MedianLatency, "burst", "latency", "p50")
+	json.Set(result.P90Latency, "burst", "latency", "p90")
+
+	json.Object("burst", "errors")
+	for name, count := range result.Errors {
+		json.Set(count, "burst", "errors", name)
+	}
+
+	if result.Failed > 0 {
+		json.Set("ERROR", "severity")
+	}
+
+	message := stringFormatter.Format("#:{0} | @:{1} | burst:{2}/{3} | min/max/avg/p50/p90={4}/{5}/{6}/{7}/{8}",
+		*attempt, target.String(), result.Successful, result.Size,
+		result.MinLatency, result.MaxLatency, result.AvgLatency, result.MedianLatency, result.P90Latency)
+	json.Set(message, "message")
+
+	io.WriteString(p.writer, json.String()+"\n")
+}
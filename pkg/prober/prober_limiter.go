@@ -0,0 +1,94 @@
+package prober
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+type (
+	// Limiter enforces process-wide constraints on probing, shared by all probers:
+	// a max rate of probes per second, and a max number of probes in flight.
+	Limiter struct {
+		mutex    sync.Mutex
+		rate     float64 // tokens added per second; 0 means no limit
+		burst    float64
+		tokens   float64
+		refilled time.Time
+		inFlight chan struct{} // `nil` means no limit
+	}
+)
+
+// NewLimiter creates a limiter allowing `rate` probes per second ( 0 means no limit )
+// and at most `maxInFlight` concurrent probes ( 0 means no limit ).
+func NewLimiter(rate float64, maxInFlight uint64) *Limiter {
+	limiter := &Limiter{
+		rate:     rate,
+		burst:    max(rate, 1.0),
+		refilled: time.Now(),
+	}
+	limiter.tokens = limiter.burst
+	if maxInFlight > 0 {
+		limiter.inFlight = make(chan struct{}, maxInFlight)
+	}
+	return limiter
+}
+
+// waitForToken blocks until the rate limit allows one more probe
+func (l *Limiter) waitForToken(ctx context.Context) error {
+	for {
+		l.mutex.Lock()
+		now := time.Now()
+		l.tokens = min(l.burst, l.tokens+now.Sub(l.refilled).Seconds()*l.rate)
+		l.refilled = now
+		if l.tokens >= 1.0 {
+			l.tokens -= 1.0
+			l.mutex.Unlock()
+			return nil
+		}
+		wait := time.Duration((1.0 - l.tokens) / l.rate * float64(time.Second))
+		l.mutex.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// acquire blocks until a probe is allowed to start; `release` must be called once it is done.
+func (l *Limiter) acquire(ctx context.Context) (release func(), err error) {
+	release = func() {}
+	if l == nil {
+		return release, nil
+	}
+
+	if l.inFlight != nil {
+		select {
+		case <-ctx.Done():
+			return release, ctx.Err()
+		case l.inFlight <- struct{}{}:
+		}
+		release = func() { <-l.inFlight }
+	}
+
+	if l.rate > 0 {
+		if err := l.waitForToken(ctx); err != nil {
+			release()
+			return func() {}, err
+		}
+	}
+
+	return release, nil
+}
+
+// startOffset spreads tasks across their interval: the same task always starts at the same offset
+func startOffset(rawTaskURL string, interval time.Duration) time.Duration {
+	hash := fnv.New64a()
+	hash.Write([]byte(rawTaskURL))
+	return time.Duration(hash.Sum64() % uint64(interval))
+}
@@ -1,26 +1,96 @@
 package prober
 
 import (
+	"math"
+	"net/netip"
 	"net/url"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/try"
+	errorx "github.com/pkg/errors"
 )
 
 type (
 	proberTaskParams struct {
 		TLS           bool
 		Timeout       time.Duration
-		DNSInterval   uint8
+		DNSInterval   uint32
 		Interval      time.Duration
+		Jitter        float64
 		LogSize       logSizeType
-		StatsInterval uint8
+		StatsInterval uint32
 		OutputFormat  string
+
+		TracerouteAfter   uint32
+		TracerouteMaxHops uint8
+
+		Mode         string
+		PMTUProtocol string
+		PMTUMax      uint16
+
+		Payload string
+		Expect  string
+
+		SourceIP        netip.Addr
+		SourcePortRange *portRange
+		BindDevice      string
+
+		TOS          *uint8
+		TrafficClass *uint8
+		Mark         *uint32
+		Priority     *uint32
+
+		CloseMode string
+
+		SYNCount    *uint8
+		UserTimeout *uint32
+
+		KeepAliveInterval time.Duration
+
+		IdleMin         time.Duration
+		IdleMax         time.Duration
+		IdleConnections uint8
+		IdleResolution  time.Duration
+
+		Burst     uint16
+		BurstHold time.Duration
+
+		ECMPFlows uint16
+
+		MaxProbes   uint64
+		MaxDuration time.Duration
+
+		Windows *probeWindows
+
+		FailureInterval   time.Duration
+		FailureThreshold  uint64
+		RecoveryThreshold uint64
+
+		MinSuccessRatio        float64
+		MaxP99                 time.Duration
+		MaxConsecutiveFailures *uint64
+
+		Concurrency string
+		MaxInFlight uint64
+
+		Quantiles []float64
+
+		StatsWindows []time.Duration
+	}
+
+	portRange struct {
+		first, last uint16
 	}
 )
 
 const (
-	PARAM_INTERVAL         = "probe_interval" // how often to probe ( seconds )
-	PARAM_TIMEOUT          = "probe_timeout"  // how long probes should wait before failing ( Milliseconds )
+	PARAM_INTERVAL         = "probe_interval" // how often to probe ( duration; bare integers are seconds )
+	PARAM_JITTER           = "probe_jitter"   // max random delay applied to each probe, as a ratio of the interval ( 0.0-1.0 )
+	PARAM_TIMEOUT          = "probe_timeout"  // how long probes should wait before failing ( duration; bare integers are Milliseconds )
 	PARAM_USE_TLS          = "use_tls"        // probe using TLS
 	PARAM_DNS_INTERVAL     = "dns_interval"   // after how many probes FQDNs should be re-resolved ( only applied for `dns+...` )
 	PARAM_LOG_SIZE         = "log_size"       // how many probes details to keep for stats
@@ -30,39 +100,183 @@ const (
 	PARAM_LOGZ_NAME        = "logz_name"
 	PARAM_LOGZ_ROTATE_SECS = "logz_rotate_secs"
 	PARAM_LOGZ_SYNC        = "logz_sync"
+
+	PARAM_TRACEROUTE_AFTER    = "traceroute_after"    // after how many consecutive failures a traceroute should be performed ( 0 disables it )
+	PARAM_TRACEROUTE_MAX_HOPS = "traceroute_max_hops" // max TTL to be used when tracing the path to the target
+
+	PARAM_MODE          = "probe_mode"    // how to probe the target: `connect`, `pmtu`, `persistent` or `idle_timeout`
+	PARAM_PMTU_PROTOCOL = "pmtu_protocol" // which DF-flagged packets to use for path MTU discovery: `udp` or `icmp`
+	PARAM_PMTU_MAX      = "pmtu_max"      // largest MTU to be tried ( defaults to the route MTU )
+
+	PARAM_PAYLOAD = "payload" // what to send after connecting ( only applied for `unix` and `persistent` probes )
+	PARAM_EXPECT  = "expect"  // what the response to `payload` should contain
+
+	PARAM_SOURCE_IP         = "source_ip"         // local IP address probes should be sent from
+	PARAM_SOURCE_PORT_RANGE = "source_port_range" // local ports probes should be sent from: `{first}-{last}` or `{port}`
+	PARAM_BIND_DEVICE       = "bind_device"       // network interface probes should be sent through ( `SO_BINDTODEVICE` )
+
+	PARAM_DSCP        = "dscp"        // DSCP to be applied to both `IP_TOS` and `IPV6_TCLASS` ( 0-63 )
+	PARAM_IP_TOS      = "ip_tos"      // raw TOS byte for IPv4 probes; overrides `dscp`
+	PARAM_IPV6_TCLASS = "ipv6_tclass" // raw traffic class for IPv6 probes; overrides `dscp`
+	PARAM_SO_MARK     = "so_mark"     // firewall mark used by policy routing ( requires `CAP_NET_ADMIN` )
+	PARAM_SO_PRIORITY = "so_priority" // protocol-defined priority for all packets sent by probes
+
+	PARAM_CLOSE_MODE = "close_mode" // how to close successful connections: `rst`, `fin` or `half_close`
+
+	PARAM_TCP_SYNCNT       = "tcp_syncnt"       // how many SYN retransmits before aborting the connection attempt ( `TCP_SYNCNT` )
+	PARAM_TCP_USER_TIMEOUT = "tcp_user_timeout" // how long transmitted data may remain unacknowledged ( duration; bare integers are Milliseconds; `TCP_USER_TIMEOUT` )
+
+	PARAM_KEEPALIVE_INTERVAL = "keepalive_interval" // how often TCP keepalives are sent by `persistent` probes without `payload` ( duration; bare integers are seconds )
+
+	PARAM_IDLE_MIN         = "idle_min"         // shortest idle period to be tested by `idle_timeout` probes ( duration; bare integers are seconds )
+	PARAM_IDLE_MAX         = "idle_max"         // longest idle period to be tested by `idle_timeout` probes ( duration; bare integers are seconds )
+	PARAM_IDLE_CONNECTIONS = "idle_connections" // how many connections to keep idle concurrently on each round
+	PARAM_IDLE_RESOLUTION  = "idle_resolution"  // stop searching when the estimate is this precise ( duration; bare integers are seconds )
+
+	PARAM_BURST      = "burst"      // how many connections each probe should open concurrently
+	PARAM_BURST_HOLD = "burst_hold" // how long to keep burst connections open before closing them ( duration; bare integers are Milliseconds )
+
+	PARAM_ECMP_FLOWS = "ecmp_flows" // how many fixed source ports ( flows ) to rotate through while keeping per-flow stats
+
+	PARAM_MAX_TARGETS = "max_targets" // how many targets a task URL may expand into: addresses ( CIDR or range ) times ports
+
+	PARAM_MAX_PROBES   = "max_probes"   // stop probing after this many probes ( 0 means no limit )
+	PARAM_MAX_DURATION = "max_duration" // stop probing after this long ( duration; bare integers are seconds; 0 means no limit )
+
+	PARAM_SCHEDULE        = "schedule"        // cron expression of when probing windows open, i/e: `*/15 * * * *`
+	PARAM_SCHEDULE_WINDOW = "schedule_window" // how long probing windows stay open ( duration; bare integers are seconds )
+	PARAM_SCHEDULE_TZ     = "schedule_tz"     // time zone of the `schedule`, i/e: `America/New_York`
+
+	PARAM_FAILURE_INTERVAL   = "failure_interval"   // how often to probe while failing ( duration; bare integers are seconds; 0 disables it )
+	PARAM_FAILURE_THRESHOLD  = "failure_threshold"  // after how many consecutive failures to switch to `failure_interval`
+	PARAM_RECOVERY_THRESHOLD = "recovery_threshold" // after how many consecutive successes to switch back to `probe_interval`
+
+	// success criteria: when probing is over, the task fails if any of them is not met
+	PARAM_MIN_SUCCESS_RATIO        = "min_success_ratio"        // min ratio of successful probes ( 0.0-1.0 )
+	PARAM_MAX_P99                  = "max_p99"                  // max p99 latency of all probes ( duration; bare integers are Milliseconds )
+	PARAM_MAX_CONSECUTIVE_FAILURES = "max_consecutive_failures" // max number of failed probes in a row
+)
+
+const (
+	PARAM_CONCURRENCY   = "concurrency"   // whether probes may overlap when they outlast `probe_interval`: `serial` or `overlap`
+	PARAM_MAX_IN_FLIGHT = "max_in_flight" // how many `overlap` probes may be in flight ( defaults to as many as `probe_timeout` allows )
+)
+
+const (
+	PARAM_QUANTILES     = "quantiles"     // comma separated latency quantiles to be reported with stats, i/e: `0.5,0.99`
+	PARAM_STATS_WINDOWS = "stats_windows" // comma separated wall-clock windows to be reported with stats ( whole seconds; empty disables them )
+)
+
+const (
+	CONCURRENCY_SERIAL  = "serial"  // probes never overlap: slots passed while probing are missed
+	CONCURRENCY_OVERLAP = "overlap" // probes start on every slot while fewer than `max_in_flight` are in flight
+)
+
+const (
+	CLOSE_MODE_RST        = "rst"        // abort the connection: skips TIME_WAIT
+	CLOSE_MODE_FIN        = "fin"        // graceful close: waits for the peer's FIN
+	CLOSE_MODE_HALF_CLOSE = "half_close" // shuts down writing, waits for the peer's FIN and then aborts
+)
+
+const maxDSCP = 63
+
+// Linux rejects `TCP_SYNCNT` values outside of this range
+const (
+	minSYNCount = 1
+	maxSYNCount = 127
+)
+
+const (
+	PROBE_MODE_CONNECT    = "connect"
+	PROBE_MODE_PMTU       = "pmtu"
+	PROBE_MODE_PERSISTENT = "persistent"
+	PROBE_MODE_IDLE       = "idle_timeout"
 )
 
 const (
 	defaultProbeInterval                = 1 * time.Second
+	minProbeInterval                    = 1 * time.Millisecond
+	defaultProbeJitter                  = 0.8787
 	defaultProbeTimeout                 = 5 * time.Second
-	defaultProbeDNSInterval uint8       = 10
+	minProbeTimeout                     = 1 * time.Millisecond
+	defaultProbeDNSInterval             = 10
 	defaultStatsInterval                = 10
 	defaultLogSize          logSizeType = 255
 	defaultOutptFormat                  = JSON_OUTPUT_FORMAT
+
+	defaultTracerouteAfter   = 0
+	defaultTracerouteMaxHops = 30
+
+	defaultMode         = PROBE_MODE_CONNECT
+	defaultPMTUProtocol = PMTU_PROTOCOL_UDP
+	defaultCloseMode    = CLOSE_MODE_RST
+
+	defaultIdleMin         = 30 * time.Second
+	defaultIdleMax         = 1 * time.Hour
+	defaultIdleConnections = 4
+	defaultIdleResolution  = 10 * time.Second
+
+	defaultBurst = 1
+
+	// first source port used by `ecmp_flows` when no `source_port_range` is provided
+	defaultECMPFirstPort uint16 = 40000
+	// per-flow stats are kept, and printed, for every flow
+	maxECMPFlows = 1024
+
+	defaultMaxTargets = 256
+
+	defaultScheduleWindow = 1 * time.Minute
+
+	defaultFailureThreshold  = 3
+	defaultRecoveryThreshold = 3
+
+	defaultConcurrency = CONCURRENCY_SERIAL
+
+	// like load averages: the last 1, 5 and 15 minutes
+	defaultStatsWindows = "1m,5m,15m"
+	minStatsWindow      = 1 * time.Second
+	maxStatsWindow      = 24 * time.Hour
 )
 
-func getProbeInterval(config *url.Values) time.Duration {
-	interval, err := strconv.Atoi(config.Get(PARAM_INTERVAL))
-	if err != nil {
-		return defaultProbeInterval
-	}
-	return time.Duration(interval) * time.Second
+func getProbeInterval(config *url.Values) (time.Duration, error) {
+	return getDuration(config, PARAM_INTERVAL, time.Second, minProbeInterval, defaultProbeInterval)
 }
 
-func getProbeTimeout(config *url.Values) time.Duration {
-	timeout, err := strconv.Atoi(config.Get(PARAM_TIMEOUT))
-	if err != nil {
-		return defaultProbeTimeout
+func getProbeJitter(config *url.Values) (float64, error) {
+	return getRatio(config, PARAM_JITTER, defaultProbeJitter)
+}
+
+func getRatio(config *url.Values, param string, defaultValue float64) (float64, error) {
+	rawRatio := config.Get(param)
+	if rawRatio == "" {
+		return defaultValue, nil
 	}
-	return time.Duration(timeout) * time.Millisecond
+	ratio, err := strconv.ParseFloat(rawRatio, 64)
+	if err != nil || ratio < 0.0 || ratio > 1.0 {
+		return 0, errorx.WithMessagef(errorInvalidParam, "%s=%s: expected a ratio between 0.0 and 1.0", param, rawRatio)
+	}
+	return ratio, nil
 }
 
-func getProbeDNSInterval(config *url.Values) uint8 {
-	dnsInterval, err := strconv.Atoi(config.Get(PARAM_DNS_INTERVAL))
+// getOptionalCount provides `nil` when the param is not set, so that 0 remains a valid count
+func getOptionalCount(config *url.Values, param string) (*uint64, error) {
+	if config.Get(param) == "" {
+		return nil, nil
+	}
+	count, err := getCount(config, param, 64, 0, 0)
 	if err != nil {
-		return defaultProbeDNSInterval
+		return nil, err
 	}
-	return uint8(dnsInterval)
+	return &count, nil
+}
+
+func getProbeTimeout(config *url.Values) (time.Duration, error) {
+	return getDuration(config, PARAM_TIMEOUT, time.Millisecond, minProbeTimeout, defaultProbeTimeout)
+}
+
+func getProbeDNSInterval(config *url.Values) (uint32, error) {
+	dnsInterval, err := getCount(config, PARAM_DNS_INTERVAL, 32, 1, defaultProbeDNSInterval)
+	return uint32(dnsInterval), err
 }
 
 func useTLS(config *url.Values) bool {
@@ -70,20 +284,14 @@ func useTLS(config *url.Values) bool {
 	return err == nil && useTLS
 }
 
-func getLogSize(config *url.Values) logSizeType {
-	logSize, err := strconv.Atoi(config.Get(PARAM_LOG_SIZE))
-	if err != nil {
-		return defaultLogSize
-	}
-	return logSizeType(logSize)
+func getLogSize(config *url.Values) (logSizeType, error) {
+	logSize, err := getCount(config, PARAM_LOG_SIZE, 16, 1, uint64(defaultLogSize))
+	return logSizeType(logSize), err
 }
 
-func getStatsInterval(config *url.Values) uint8 {
-	statsInterval, err := strconv.Atoi(config.Get(PARAM_STATS_INTERVAL))
-	if err != nil {
-		return defaultStatsInterval
-	}
-	return uint8(statsInterval)
+func getStatsInterval(config *url.Values) (uint32, error) {
+	statsInterval, err := getCount(config, PARAM_STATS_INTERVAL, 32, 1, defaultStatsInterval)
+	return uint32(statsInterval), err
 }
 
 func getOutputFormat(config *url.Values) string {
@@ -94,25 +302,539 @@ func getOutputFormat(config *url.Values) string {
 	return outputFormat
 }
 
-func newProberTaskParams(taskURL *url.URL) *proberTaskParams {
+func getTracerouteAfter(config *url.Values) (uint32, error) {
+	tracerouteAfter, err := getCount(config, PARAM_TRACEROUTE_AFTER, 32, 0, defaultTracerouteAfter)
+	return uint32(tracerouteAfter), err
+}
+
+func getTracerouteMaxHops(config *url.Values) (uint8, error) {
+	maxHops, err := getCount(config, PARAM_TRACEROUTE_MAX_HOPS, 8, 1, defaultTracerouteMaxHops)
+	return uint8(maxHops), err
+}
+
+// getChoice rejects values that are not one of `choices` instead of falling back to `defaultValue`
+func getChoice(config *url.Values, param, defaultValue string, choices ...string) (string, error) {
+	value := config.Get(param)
+	if value == "" {
+		return defaultValue, nil
+	}
+	if !slices.Contains(choices, value) {
+		return "", errorx.WithMessagef(errorInvalidParam, "%s=%s: expected one of %s",
+			param, value, strings.Join(choices, ", "))
+	}
+	return value, nil
+}
+
+func getMode(config *url.Values) (string, error) {
+	return getChoice(config, PARAM_MODE, defaultMode,
+		PROBE_MODE_CONNECT, PROBE_MODE_PMTU, PROBE_MODE_PERSISTENT, PROBE_MODE_IDLE)
+}
+
+// isLongRunning tells whether each probe of `mode` discovers a property of the path over many intervals
+func isLongRunning(mode string) bool {
+	return mode == PROBE_MODE_PMTU || mode == PROBE_MODE_IDLE
+}
+
+func getPMTUProtocol(config *url.Values) (string, error) {
+	return getChoice(config, PARAM_PMTU_PROTOCOL, defaultPMTUProtocol, PMTU_PROTOCOL_UDP, PMTU_PROTOCOL_ICMP)
+}
+
+func getPMTUMax(config *url.Values) (uint16, error) {
+	maxMTU, err := getCount(config, PARAM_PMTU_MAX, 16, 0, 0)
+	return uint16(maxMTU), err
+}
+
+// getSourceIP provides the zero `netip.Addr` when `source_ip` is not set: the kernel picks the source address;
+// it must belong to the same family as the task's targets, as probes could not be sent from it otherwise.
+func getSourceIP(config *url.Values, scheme string) (netip.Addr, error) {
+	rawSourceIP := config.Get(PARAM_SOURCE_IP)
+	if rawSourceIP == "" {
+		return netip.Addr{}, nil
+	}
+	sourceIP, err := netip.ParseAddr(rawSourceIP)
+	if err != nil {
+		return netip.Addr{}, errorx.WithMessagef(errorInvalidParam, "%s=%s: expected an IP address", PARAM_SOURCE_IP, rawSourceIP)
+	}
+	sourceIP = sourceIP.Unmap()
+
+	switch scheme {
+	case RAW_IPv4_SCHEME, DNS_IPv4_SCHEME:
+		if !sourceIP.Is4() {
+			return netip.Addr{}, errorx.WithMessagef(errorInvalidParam, "%s=%s: expected an IPv4 address for %s tasks",
+				PARAM_SOURCE_IP, rawSourceIP, scheme)
+		}
+	case RAW_IPv6_SCHEME, DNS_IPv6_SCHEME:
+		if !sourceIP.Is6() {
+			return netip.Addr{}, errorx.WithMessagef(errorInvalidParam, "%s=%s: expected an IPv6 address for %s tasks",
+				PARAM_SOURCE_IP, rawSourceIP, scheme)
+		}
+	}
+	return sourceIP, nil
+}
+
+func getSourcePortRange(config *url.Values) (*portRange, error) {
+	rawRange := config.Get(PARAM_SOURCE_PORT_RANGE)
+	if rawRange == "" {
+		return nil, nil
+	}
+
+	rawFirst, rawLast, isRange := strings.Cut(rawRange, "-")
+	if !isRange {
+		rawLast = rawFirst
+	}
+
+	first, err := strconv.ParseUint(rawFirst, 10, 16)
+	if err == nil && first > 0 {
+		var last uint64
+		if last, err = strconv.ParseUint(rawLast, 10, 16); err == nil && last >= first {
+			return &portRange{uint16(first), uint16(last)}, nil
+		}
+	}
+	return nil, errorx.WithMessagef(errorInvalidParam, "%s=%s: expected `{first}-{last}` or `{port}` within 1 and %d",
+		PARAM_SOURCE_PORT_RANGE, rawRange, math.MaxUint16)
+}
+
+func (r *portRange) size() uint64 {
+	return uint64(r.last-r.first) + 1
+}
+
+func (r *portRange) port(index uint64) uint16 {
+	return r.first + uint16(index%r.size())
+}
+
+// getOptionalUint provides `nil` when `param` is not set; values that do not fit in `bitSize` bits are rejected
+func getOptionalUint(config *url.Values, param string, bitSize int) (*uint64, error) {
+	if config.Get(param) == "" {
+		return nil, nil
+	}
+	value, err := getCount(config, param, bitSize, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &value, nil
+}
+
+func getOptionalUint8(config *url.Values, param string) (*uint8, error) {
+	value, err := getOptionalUint(config, param, 8)
+	if value == nil {
+		return nil, err
+	}
+	value8 := uint8(*value)
+	return &value8, nil
+}
+
+func getOptionalUint32(config *url.Values, param string) (*uint32, error) {
+	value, err := getOptionalUint(config, param, 32)
+	if value == nil {
+		return nil, err
+	}
+	value32 := uint32(*value)
+	return &value32, nil
+}
+
+// getTrafficClasses provides the TOS ( IPv4 ) and traffic class ( IPv6 ) bytes:
+// DSCP fills the 6 most significant bits, explicit raw values take precedence.
+func getTrafficClasses(config *url.Values) (TOS, trafficClass *uint8, err error) {
+	defer err2.Handle(&err)
+
+	if dscp := try.To1(getOptionalUint8(config, PARAM_DSCP)); dscp != nil {
+		if *dscp > maxDSCP {
+			return nil, nil, errorx.WithMessagef(errorInvalidParam, "%s=%d: expected an integer between 0 and %d",
+				PARAM_DSCP, *dscp, maxDSCP)
+		}
+		dsField := *dscp << 2
+		TOS, trafficClass = &dsField, &dsField
+	}
+	if rawTOS := try.To1(getOptionalUint8(config, PARAM_IP_TOS)); rawTOS != nil {
+		TOS = rawTOS
+	}
+	if rawTrafficClass := try.To1(getOptionalUint8(config, PARAM_IPV6_TCLASS)); rawTrafficClass != nil {
+		trafficClass = rawTrafficClass
+	}
+	return TOS, trafficClass, nil
+}
+
+func getKeepAliveInterval(config *url.Values, interval time.Duration) (time.Duration, error) {
+	return getDuration(config, PARAM_KEEPALIVE_INTERVAL, time.Second, time.Second, interval)
+}
+
+// getDuration accepts Go duration strings ( i/e: `250ms`, `1m30s` ); bare integers
+// are still interpreted using the param's original `unit` to remain backwards compatible.
+func getDuration(config *url.Values, param string, unit, min, defaultValue time.Duration) (time.Duration, error) {
+	rawValue := config.Get(param)
+	if rawValue == "" {
+		return defaultValue, nil
+	}
+	return parseDuration(param, rawValue, unit, min)
+}
+
+func parseDuration(param, rawValue string, unit, min time.Duration) (time.Duration, error) {
+	duration, err := time.ParseDuration(rawValue)
+	if value, intErr := strconv.ParseInt(rawValue, 10, 64); intErr == nil {
+		duration, err = time.Duration(value)*unit, nil
+		if value > int64(math.MaxInt64/unit) || value < 0 {
+			err = strconv.ErrRange
+		}
+	}
+
+	if err != nil || duration < min {
+		return 0, errorx.WithMessagef(errorInvalidParam, "%s=%s: expected a duration of at least %s", param, rawValue, min)
+	}
+	return duration, nil
+}
+
+// getCount rejects counts that do not fit in `bitSize` bits instead of wrapping them
+func getCount(config *url.Values, param string, bitSize int, min, defaultValue uint64) (uint64, error) {
+	rawValue := config.Get(param)
+	if rawValue == "" {
+		return defaultValue, nil
+	}
+
+	count, err := strconv.ParseUint(rawValue, 10, bitSize)
+	if err != nil || count < min {
+		return 0, errorx.WithMessagef(errorInvalidParam, "%s=%s: expected an integer between %d and %d",
+			param, rawValue, min, uint64(math.MaxUint64)>>(64-bitSize))
+	}
+	return count, nil
+}
+
+// getIdleRange provides the idle periods `idle_timeout` probes search within, and how precise the search is
+func getIdleRange(config *url.Values) (idleMin, idleMax, resolution time.Duration, err error) {
+	defer err2.Handle(&err)
+
+	idleMin = try.To1(getDuration(config, PARAM_IDLE_MIN, time.Second, time.Second, defaultIdleMin))
+	idleMax = try.To1(getDuration(config, PARAM_IDLE_MAX, time.Second, time.Second, defaultIdleMax))
+	resolution = try.To1(getDuration(config, PARAM_IDLE_RESOLUTION, time.Second, time.Millisecond, defaultIdleResolution))
+
+	if idleMin >= idleMax {
+		return 0, 0, 0, errorx.WithMessagef(errorInvalidParam, "%s=%s: expected a duration longer than %s=%s",
+			PARAM_IDLE_MAX, idleMax, PARAM_IDLE_MIN, idleMin)
+	}
+	if resolution >= idleMax-idleMin {
+		return 0, 0, 0, errorx.WithMessagef(errorInvalidParam, "%s=%s: expected a duration shorter than %s - %s ( %s )",
+			PARAM_IDLE_RESOLUTION, resolution, PARAM_IDLE_MAX, PARAM_IDLE_MIN, idleMax-idleMin)
+	}
+	return idleMin, idleMax, resolution, nil
+}
+
+func getIdleConnections(config *url.Values) (uint8, error) {
+	connections, err := getCount(config, PARAM_IDLE_CONNECTIONS, 8, 2, defaultIdleConnections)
+	return uint8(connections), err
+}
+
+func getBurst(config *url.Values) (uint16, error) {
+	burst, err := getCount(config, PARAM_BURST, 16, 1, defaultBurst)
+	return uint16(burst), err
+}
+
+func getBurstHold(config *url.Values) (time.Duration, error) {
+	return getDuration(config, PARAM_BURST_HOLD, time.Millisecond, 0, 0)
+}
+
+// getECMPFlows provides how many flows to keep stats for, and the source ports they use:
+// every source port in `source_port_range` is a flow, otherwise they start at `defaultECMPFirstPort`.
+func getECMPFlows(config *url.Values, sourcePortRange *portRange) (uint16, *portRange, error) {
+	flows, err := getCount(config, PARAM_ECMP_FLOWS, 16, 0, 0)
+	if err != nil || flows == 0 {
+		return 0, sourcePortRange, err
+	}
+
+	if sourcePortRange != nil {
+		flows = sourcePortRange.size()
+	}
+	if flows > maxECMPFlows && sourcePortRange != nil {
+		return 0, nil, errorx.WithMessagef(errorInvalidParam, "%s=%s: expected at most %d ports when %s is set",
+			PARAM_SOURCE_PORT_RANGE, config.Get(PARAM_SOURCE_PORT_RANGE), maxECMPFlows, PARAM_ECMP_FLOWS)
+	}
+	if flows > maxECMPFlows {
+		return 0, nil, errorx.WithMessagef(errorInvalidParam, "%s=%s: expected at most %d flows",
+			PARAM_ECMP_FLOWS, config.Get(PARAM_ECMP_FLOWS), maxECMPFlows)
+	}
+	if sourcePortRange == nil {
+		sourcePortRange = &portRange{defaultECMPFirstPort, defaultECMPFirstPort + uint16(flows) - 1}
+	}
+	return uint16(flows), sourcePortRange, nil
+}
+
+func getSYNCount(config *url.Values) (*uint8, error) {
+	if config.Get(PARAM_TCP_SYNCNT) == "" {
+		return nil, nil
+	}
+	synCount, err := getCount(config, PARAM_TCP_SYNCNT, 8, minSYNCount, 0)
+	if err != nil || synCount > maxSYNCount {
+		return nil, errorx.WithMessagef(errorInvalidParam, "%s=%s: expected an integer between %d and %d",
+			PARAM_TCP_SYNCNT, config.Get(PARAM_TCP_SYNCNT), minSYNCount, maxSYNCount)
+	}
+	synCount8 := uint8(synCount)
+	return &synCount8, nil
+}
+
+// getUserTimeout provides `TCP_USER_TIMEOUT` in Milliseconds, as expected by the socket option
+func getUserTimeout(config *url.Values) (*uint32, error) {
+	if config.Get(PARAM_TCP_USER_TIMEOUT) == "" {
+		return nil, nil
+	}
+	timeout, err := getDuration(config, PARAM_TCP_USER_TIMEOUT, time.Millisecond, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	if timeout.Milliseconds() > math.MaxUint32 {
+		return nil, errorx.WithMessagef(errorInvalidParam, "%s=%s: too long", PARAM_TCP_USER_TIMEOUT, config.Get(PARAM_TCP_USER_TIMEOUT))
+	}
+	userTimeout := uint32(timeout.Milliseconds())
+	return &userTimeout, nil
+}
+
+func getWindows(config *url.Values) (windows *probeWindows, err error) {
+	defer err2.Handle(&err, "getWindows")
+
+	expression := config.Get(PARAM_SCHEDULE)
+	if expression == "" {
+		return nil, nil
+	}
+
+	length := try.To1(getDuration(config, PARAM_SCHEDULE_WINDOW, time.Second, time.Second, defaultScheduleWindow))
+
+	location := time.UTC
+	if tz := config.Get(PARAM_SCHEDULE_TZ); tz != "" {
+		location = try.To1(time.LoadLocation(tz))
+	}
+
+	return newProbeWindows(expression, length, location)
+}
+
+func getMaxTargets(config *url.Values) (uint32, error) {
+	maxTargets, err := getCount(config, PARAM_MAX_TARGETS, 32, 1, defaultMaxTargets)
+	return uint32(maxTargets), err
+}
+
+func getQuantiles(config *url.Values) ([]float64, error) {
+	rawQuantiles := config.Get(PARAM_QUANTILES)
+	if rawQuantiles == "" {
+		return defaultQuantiles, nil
+	}
+
+	var quantiles []float64
+	for _, rawQuantile := range strings.Split(rawQuantiles, ",") {
+		quantile, err := strconv.ParseFloat(strings.TrimSpace(rawQuantile), 64)
+		if err != nil || quantile <= 0 || quantile >= 1 {
+			return nil, errorx.WithMessagef(errorInvalidParam, "%s=%s: expected quantiles between 0.0 and 1.0 ( exclusive )",
+				PARAM_QUANTILES, rawQuantiles)
+		}
+		if !slices.Contains(quantiles, quantile) {
+			quantiles = append(quantiles, quantile)
+		}
+	}
+	slices.Sort(quantiles)
+	return quantiles, nil
+}
+
+// getStatsWindows provides no windows when `stats_windows` is explicitly empty, and the default ones when it is not set
+func getStatsWindows(config *url.Values) ([]time.Duration, error) {
+	if !config.Has(PARAM_STATS_WINDOWS) {
+		config = &url.Values{PARAM_STATS_WINDOWS: {defaultStatsWindows}}
+	}
+	rawWindows := config.Get(PARAM_STATS_WINDOWS)
+	if strings.TrimSpace(rawWindows) == "" {
+		return nil, nil
+	}
+
+	var windows []time.Duration
+	for _, rawWindow := range strings.Split(rawWindows, ",") {
+		window, err := parseDuration(PARAM_STATS_WINDOWS, strings.TrimSpace(rawWindow), time.Second, minStatsWindow)
+		if err != nil {
+			return nil, err
+		}
+		if window > maxStatsWindow || window%time.Second != 0 {
+			return nil, errorx.WithMessagef(errorInvalidParam, "%s=%s: expected windows of whole seconds, up to %s",
+				PARAM_STATS_WINDOWS, rawWindows, maxStatsWindow)
+		}
+		if !slices.Contains(windows, window) {
+			windows = append(windows, window)
+		}
+	}
+	slices.Sort(windows)
+	return windows, nil
+}
+
+// getConcurrency provides the concurrency policy, and how many probes may be in flight;
+// only `connect` probes are independent from each other, so only they may overlap.
+// Probes sent from fixed source ports may not overlap: source ports are rotated, so a hung probe
+// would still hold the port handed to a later one, which would fail locally with `EADDRNOTAVAIL`.
+func getConcurrency(config *url.Values, mode string, sourcePortRange *portRange, interval, timeout time.Duration) (string, uint64, error) {
+	concurrency := config.Get(PARAM_CONCURRENCY)
+	switch concurrency {
+	case "":
+		return defaultConcurrency, 1, nil
+	case CONCURRENCY_SERIAL:
+		return concurrency, 1, nil
+	case CONCURRENCY_OVERLAP:
+	default:
+		return "", 0, errorx.WithMessagef(errorInvalidParam, "%s=%s: expected `%s` or `%s`",
+			PARAM_CONCURRENCY, concurrency, CONCURRENCY_SERIAL, CONCURRENCY_OVERLAP)
+	}
+
+	if mode != PROBE_MODE_CONNECT {
+		return "", 0, errorx.WithMessagef(errorInvalidParam, "%s=%s: not supported by `%s` probes",
+			PARAM_CONCURRENCY, concurrency, mode)
+	}
+	if sourcePortRange != nil {
+		return "", 0, errorx.WithMessagef(errorInvalidParam, "%s=%s: not supported with fixed source ports ( `%s` or `%s` )",
+			PARAM_CONCURRENCY, concurrency, PARAM_SOURCE_PORT_RANGE, PARAM_ECMP_FLOWS)
+	}
+
+	// enough probes to never miss a slot while the target hangs
+	defaultMaxInFlight := max(uint64((timeout+interval-1)/interval), 2)
+	maxInFlight, err := getCount(config, PARAM_MAX_IN_FLIGHT, 16, 1, defaultMaxInFlight)
+	return concurrency, maxInFlight, err
+}
+
+func getCloseMode(config *url.Values) (string, error) {
+	return getChoice(config, PARAM_CLOSE_MODE, defaultCloseMode, CLOSE_MODE_RST, CLOSE_MODE_FIN, CLOSE_MODE_HALF_CLOSE)
+}
+
+func getLogRotate(config *url.Values) (time.Duration, error) {
+	return getDuration(config, PARAM_LOGZ_ROTATE_SECS, time.Second, time.Second, JSON_LOG_ROTATE_SECS*time.Second)
+}
+
+func getLogSync(config *url.Values) (bool, error) {
+	rawSync := config.Get(PARAM_LOGZ_SYNC)
+	if rawSync == "" {
+		return true, nil
+	}
+	logSync, err := strconv.ParseBool(rawSync)
+	if err != nil {
+		return false, errorx.WithMessagef(errorInvalidParam, "%s=%s: expected a boolean", PARAM_LOGZ_SYNC, rawSync)
+	}
+	return logSync, nil
+}
+
+func newProberTaskParams(taskURL *url.URL) (params *proberTaskParams, err error) {
+	defer err2.Handle(&err, "newProberTaskParams")
+
 	taskParams := taskURL.Query()
 
 	config := &taskParams
-	interval := getProbeInterval(config)
-	timeout := getProbeTimeout(config)
+	interval := try.To1(getProbeInterval(config))
+	jitter := try.To1(getProbeJitter(config))
+	timeout := try.To1(getProbeTimeout(config))
 	useTLS := useTLS(config)
-	dnsInterval := getProbeDNSInterval(config)
-	logSize := getLogSize(config)
-	statsInterval := getStatsInterval(config)
+	dnsInterval := try.To1(getProbeDNSInterval(config))
+	logSize := try.To1(getLogSize(config))
+	statsInterval := try.To1(getStatsInterval(config))
 	outputFormat := getOutputFormat(config)
+	tracerouteAfter := try.To1(getTracerouteAfter(config))
+	tracerouteMaxHops := try.To1(getTracerouteMaxHops(config))
+	// printers read `logz_*` params from the task URL: invalid ones must still fail the task
+	try.To1(getLogRotate(config))
+	try.To1(getLogSync(config))
+	mode := try.To1(getMode(config))
+	pmtuProtocol := try.To1(getPMTUProtocol(config))
+	pmtuMax := try.To1(getPMTUMax(config))
+	payload := config.Get(PARAM_PAYLOAD)
+	expect := config.Get(PARAM_EXPECT)
+	sourceIP := try.To1(getSourceIP(config, taskURL.Scheme))
+	sourcePortRange := try.To1(getSourcePortRange(config))
+	bindDevice := config.Get(PARAM_BIND_DEVICE)
+	TOS, trafficClass := try.To2(getTrafficClasses(config))
+	mark := try.To1(getOptionalUint32(config, PARAM_SO_MARK))
+	priority := try.To1(getOptionalUint32(config, PARAM_SO_PRIORITY))
+	closeMode := try.To1(getCloseMode(config))
+	synCount := try.To1(getSYNCount(config))
+	userTimeout := try.To1(getUserTimeout(config))
+	keepAliveInterval := try.To1(getKeepAliveInterval(config, interval))
+	idleMin, idleMax, idleResolution := try.To3(getIdleRange(config))
+	idleConnections := try.To1(getIdleConnections(config))
+	burst := try.To1(getBurst(config))
+	burstHold := try.To1(getBurstHold(config))
+	ECMPFlows, sourcePortRange := try.To2(getECMPFlows(config, sourcePortRange))
+	if sourcePortRange != nil && uint64(burst) > sourcePortRange.size() {
+		// connections within a burst are concurrent: none of them may share its source port
+		return nil, errorx.WithMessagef(errorInvalidParam, "%s=%d: expected at most %d connections, as many as source ports",
+			PARAM_BURST, burst, sourcePortRange.size())
+	}
+	if sourcePortRange != nil && closeMode != CLOSE_MODE_RST {
+		// connections closed gracefully linger in TIME_WAIT: their source port cannot be reused meanwhile
+		return nil, errorx.WithMessagef(errorInvalidParam, "%s=%s: fixed source ports require %s=%s",
+			PARAM_CLOSE_MODE, closeMode, PARAM_CLOSE_MODE, CLOSE_MODE_RST)
+	}
+	maxProbes := try.To1(getCount(config, PARAM_MAX_PROBES, 64, 0, 0))
+	maxDuration := try.To1(getDuration(config, PARAM_MAX_DURATION, time.Second, 0, 0))
+	windows := try.To1(getWindows(config))
+	failureInterval := try.To1(getDuration(config, PARAM_FAILURE_INTERVAL, time.Second, 0, 0))
+	failureThreshold := try.To1(getCount(config, PARAM_FAILURE_THRESHOLD, 64, 1, defaultFailureThreshold))
+	recoveryThreshold := try.To1(getCount(config, PARAM_RECOVERY_THRESHOLD, 64, 1, defaultRecoveryThreshold))
+	minSuccessRatio := try.To1(getRatio(config, PARAM_MIN_SUCCESS_RATIO, 0))
+	maxP99 := try.To1(getDuration(config, PARAM_MAX_P99, time.Millisecond, 0, 0))
+	maxConsecutiveFailures := try.To1(getOptionalCount(config, PARAM_MAX_CONSECUTIVE_FAILURES))
+	concurrency, maxInFlight := try.To2(getConcurrency(config, mode, sourcePortRange, interval, timeout))
+	quantiles := try.To1(getQuantiles(config))
+	statsWindows := try.To1(getStatsWindows(config))
 
 	return &proberTaskParams{
 		Interval:      interval,
+		Jitter:        jitter,
 		Timeout:       timeout,
 		TLS:           useTLS,
 		DNSInterval:   dnsInterval,
 		LogSize:       logSize,
 		StatsInterval: statsInterval,
 		OutputFormat:  outputFormat,
-	}
+
+		TracerouteAfter:   tracerouteAfter,
+		TracerouteMaxHops: tracerouteMaxHops,
+
+		Mode:         mode,
+		PMTUProtocol: pmtuProtocol,
+		PMTUMax:      pmtuMax,
+
+		Payload: payload,
+		Expect:  expect,
+
+		SourceIP:        sourceIP,
+		SourcePortRange: sourcePortRange,
+		BindDevice:      bindDevice,
+
+		TOS:          TOS,
+		TrafficClass: trafficClass,
+		Mark:         mark,
+		Priority:     priority,
+
+		CloseMode: closeMode,
+
+		SYNCount:    synCount,
+		UserTimeout: userTimeout,
+
+		KeepAliveInterval: keepAliveInterval,
+
+		IdleMin:         idleMin,
+		IdleMax:         idleMax,
+		IdleConnections: idleConnections,
+		IdleResolution:  idleResolution,
+
+		Burst:     burst,
+		BurstHold: burstHold,
+
+		ECMPFlows: ECMPFlows,
+
+		MaxProbes:   maxProbes,
+		MaxDuration: maxDuration,
+
+		Windows: windows,
+
+		FailureInterval:   failureInterval,
+		FailureThreshold:  failureThreshold,
+		RecoveryThreshold: recoveryThreshold,
+
+		MinSuccessRatio:        minSuccessRatio,
+		MaxP99:                 maxP99,
+		MaxConsecutiveFailures: maxConsecutiveFailures,
+
+		Concurrency: concurrency,
+		MaxInFlight: maxInFlight,
+
+		Quantiles: quantiles,
+
+		StatsWindows: statsWindows,
+	}, nil
 }
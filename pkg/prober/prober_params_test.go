@@ -0,0 +1,117 @@
+package prober
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+)
+
+func TestNewProberTaskParamsRejectsInvalidValues(t *testing.T) {
+	tests := []string{
+		"dscp=64",
+		"ip_tos=256",
+		"ipv6_tclass=-1",
+		"tcp_syncnt=300",
+		"tcp_syncnt=0",
+		"tcp_syncnt=128",
+		"tcp_syncnt=200",
+		"so_mark=-1",
+		"so_priority=4294967296",
+		"probe_mode=pmtu2",
+		"pmtu_protocol=tcp",
+		"close_mode=FIN",
+		"source_port_range=0-10",
+		"source_port_range=2000-1000",
+		"source_port_range=65536",
+		"source_ip=10.0.0",
+		"source_port_range=40000-40003&burst=5",
+		"ecmp_flows=2&burst=3",
+		"source_ip=eth0",
+		"source_ip=::1",
+		"concurrency=overlap&source_port_range=40000-40010",
+		"concurrency=overlap&ecmp_flows=8",
+		"logz_rotate_secs=0",
+		"logz_rotate_secs=10x",
+		"logz_sync=maybe",
+		"stats_windows=1500ms",
+		"stats_windows=1m,25h",
+		"stats_windows=500ms",
+		"idle_min=20s&idle_max=5s",
+		"idle_min=30s&idle_max=30s",
+		"idle_min=30s&idle_max=40s&idle_resolution=10s",
+	}
+
+	for _, query := range tests {
+		t.Run(query, func(t *testing.T) {
+			taskURL := &url.URL{Scheme: RAW_IPv4_SCHEME, Host: "127.0.0.1:80", RawQuery: query}
+			if _, err := newProberTaskParams(taskURL); !errors.Is(err, errorInvalidParam) {
+				t.Errorf("newProberTaskParams(%s) error = %v, want %v", query, err, errorInvalidParam)
+			}
+		})
+	}
+}
+
+func TestNewProberTaskParamsAcceptsValidValues(t *testing.T) {
+	tests := []string{
+		"",
+		"dscp=63",
+		"ip_tos=255",
+		"tcp_syncnt=6",
+		"tcp_syncnt=1",
+		"tcp_syncnt=127",
+		"so_mark=0",
+		"probe_mode=pmtu",
+		"pmtu_protocol=icmp",
+		"close_mode=fin",
+		"source_port_range=40000-40010",
+		"source_port_range=40000",
+		"source_ip=127.0.0.1",
+		"source_port_range=40000-40003&burst=4",
+		"concurrency=overlap&max_in_flight=8",
+		"source_ip=::ffff:127.0.0.1",
+		"logz_rotate_secs=5m",
+		"logz_sync=false",
+		"stats_windows=",
+		"stats_windows=90s,1h,24h",
+		"idle_min=30s&idle_max=40s&idle_resolution=5s",
+	}
+
+	for _, query := range tests {
+		t.Run(query, func(t *testing.T) {
+			taskURL := &url.URL{Scheme: RAW_IPv4_SCHEME, Host: "127.0.0.1:80", RawQuery: query}
+			if _, err := newProberTaskParams(taskURL); err != nil {
+				t.Errorf("newProberTaskParams(%s) error = %v", query, err)
+			}
+		})
+	}
+}
+
+func TestGetSourceIPFamily(t *testing.T) {
+	tests := []struct {
+		scheme   string
+		sourceIP string
+		valid    bool
+	}{
+		{RAW_IPv4_SCHEME, "10.0.0.1", true},
+		{RAW_IPv4_SCHEME, "::ffff:10.0.0.1", true},
+		{RAW_IPv4_SCHEME, "::1", false},
+		{DNS_IPv4_SCHEME, "fe80::1", false},
+		{RAW_IPv6_SCHEME, "::1", true},
+		{RAW_IPv6_SCHEME, "10.0.0.1", false},
+		{DNS_IPv6_SCHEME, "127.0.0.1", false},
+		{DNS_IPv6_SCHEME, "2001:db8::1", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scheme+"/"+test.sourceIP, func(t *testing.T) {
+			config := &url.Values{PARAM_SOURCE_IP: {test.sourceIP}}
+			_, err := getSourceIP(config, test.scheme)
+			if test.valid && err != nil {
+				t.Errorf("getSourceIP() error = %v", err)
+			}
+			if !test.valid && !errors.Is(err, errorInvalidParam) {
+				t.Errorf("getSourceIP() error = %v, want %v", err, errorInvalidParam)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package prober
+
+import (
+	"math"
+	"time"
+)
+
+type (
+	// rollingBucket aggregates all probes completed within the same slice of a window
+	rollingBucket struct {
+		index          int64 // which slice of time since the epoch the bucket accounts for
+		probes         uint64
+		failures       uint64
+		latencySum     float64
+		latencySquares float64
+		minLatency     float64
+		maxLatency     float64
+	}
+
+	// rollingStats describes the probes completed within a wall-clock window;
+	// latencies only account for successful probes, failures are accounted as unavailability.
+	rollingStats struct {
+		Window            time.Duration
+		Probes            uint64
+		Failures          uint64
+		Availability      float64
+		MinLatency        float64
+		MaxLatency        float64
+		AverageLatency    float64
+		StandardDeviation float64
+	}
+
+	// rollingWindow slides by `1/rollingBuckets` of its span: long windows need no more buckets than short ones
+	rollingWindow struct {
+		window     time.Duration
+		bucketSize time.Duration
+		buckets    []rollingBucket
+	}
+
+	// proberTaskRolling keeps stats for fixed wall-clock windows ( i/e: the last 1, 5 and 15 minutes ):
+	// unlike `log_size`, their span does not depend on how often probes are sent.
+	proberTaskRolling struct {
+		windows []*rollingWindow
+	}
+)
+
+// how many buckets each window is split into: the oldest one is dropped as a whole,
+// so windows span between `( rollingBuckets - 1 ) / rollingBuckets` and all of their duration.
+const rollingBuckets = 60
+
+func newProberTaskRolling(windows []time.Duration) *proberTaskRolling {
+	if len(windows) == 0 {
+		return nil
+	}
+	rolling := &proberTaskRolling{windows: make([]*rollingWindow, len(windows))}
+	for index, window := range windows {
+		rolling.windows[index] = &rollingWindow{
+			window:     window,
+			bucketSize: window / rollingBuckets,
+			buckets:    make([]rollingBucket, rollingBuckets),
+		}
+	}
+	return rolling
+}
+
+func (r *proberTaskRolling) observe(at time.Time, rtt float64, err error) {
+	for _, window := range r.windows {
+		window.observe(at, rtt, err)
+	}
+}
+
+func (w *rollingWindow) observe(at time.Time, rtt float64, err error) {
+	index := at.UnixNano() / int64(w.bucketSize)
+	bucket := &w.buckets[index%rollingBuckets]
+	if bucket.index != index {
+		// the bucket belonged to a slice of time that is already out of the window
+		*bucket = rollingBucket{index: index, minLatency: math.MaxFloat64}
+	}
+
+	bucket.probes += 1
+	if err != nil {
+		bucket.failures += 1
+		return
+	}
+
+	bucket.latencySum += rtt
+	bucket.latencySquares += rtt * rtt
+	bucket.minLatency = min(bucket.minLatency, rtt)
+	bucket.maxLatency = max(bucket.maxLatency, rtt)
+}
+
+// evaluate computes the stats of all windows ending at `now`
+func (r *proberTaskRolling) evaluate(now time.Time) []*rollingStats {
+	windows := make([]*rollingStats, len(r.windows))
+	for index, window := range r.windows {
+		windows[index] = window.evaluate(now)
+	}
+	return windows
+}
+
+func (w *rollingWindow) evaluate(now time.Time) *rollingStats {
+	last := now.UnixNano() / int64(w.bucketSize)
+	stats := &rollingStats{Window: w.window, MinLatency: math.MaxFloat64}
+	var latencySum, latencySquares float64
+
+	for index := last - rollingBuckets + 1; index <= last; index++ {
+		bucket := &w.buckets[index%rollingBuckets]
+		if bucket.index != index {
+			continue
+		}
+		stats.Probes += bucket.probes
+		stats.Failures += bucket.failures
+		latencySum += bucket.latencySum
+		latencySquares += bucket.latencySquares
+		stats.MinLatency = min(stats.MinLatency, bucket.minLatency)
+		stats.MaxLatency = max(stats.MaxLatency, bucket.maxLatency)
+	}
+
+	successful := stats.Probes - stats.Failures
+	if stats.Probes > 0 {
+		stats.Availability = float64(successful) / float64(stats.Probes)
+	}
+	if successful == 0 {
+		stats.MinLatency = 0.0
+	} else {
+		stats.AverageLatency = latencySum / float64(successful)
+	}
+	if successful > 1 {
+		// sample variance, as computed for the `log_size` window
+		variance := (latencySquares - float64(successful)*stats.AverageLatency*stats.AverageLatency) / float64(successful-1)
+		stats.StandardDeviation = math.Sqrt(math.Max(variance, 0.0))
+	}
+
+	return stats
+}
@@ -0,0 +1,91 @@
+package prober
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+)
+
+var rollingStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestProberTaskRollingWindows(t *testing.T) {
+	rolling := newProberTaskRolling([]time.Duration{time.Minute, 24 * time.Hour})
+
+	for _, window := range rolling.windows {
+		if len(window.buckets) != rollingBuckets {
+			t.Fatalf("%s window has %d buckets, want %d", window.window, len(window.buckets), rollingBuckets)
+		}
+	}
+
+	// 1 probe per second for 2 minutes: the first one failed
+	for second := range 120 {
+		var err error
+		if second == 0 {
+			err = errors.New("connection refused")
+		}
+		rolling.observe(rollingStart.Add(time.Duration(second)*time.Second), float64(second), err)
+	}
+
+	stats := rolling.evaluate(rollingStart.Add(119 * time.Second))
+
+	minute := stats[0]
+	if minute.Probes != 60 || minute.Failures != 0 {
+		t.Errorf("1m window: probes/failures = %d/%d, want 60/0", minute.Probes, minute.Failures)
+	}
+	if minute.MinLatency != 60 || minute.MaxLatency != 119 || minute.AverageLatency != 89.5 {
+		t.Errorf("1m window: min/max/avg = %v/%v/%v, want 60/119/89.5",
+			minute.MinLatency, minute.MaxLatency, minute.AverageLatency)
+	}
+
+	day := stats[1]
+	if day.Probes != 120 || day.Failures != 1 {
+		t.Errorf("24h window: probes/failures = %d/%d, want 120/1", day.Probes, day.Failures)
+	}
+	if want := 119.0 / 120.0; day.Availability != want {
+		t.Errorf("24h window: availability = %v, want %v", day.Availability, want)
+	}
+	if day.MinLatency != 1 || day.MaxLatency != 119 {
+		t.Errorf("24h window: min/max = %v/%v, want 1/119", day.MinLatency, day.MaxLatency)
+	}
+
+	// once a window passes without probes, nothing is left in it
+	if stats := rolling.evaluate(rollingStart.Add(200 * time.Second)); stats[0].Probes != 0 {
+		t.Errorf("1m window: %d probes after a minute without probes, want 0", stats[0].Probes)
+	}
+}
+
+func TestGetStatsWindows(t *testing.T) {
+	tests := []struct {
+		query   string
+		windows []time.Duration
+	}{
+		{"", []time.Duration{time.Minute, 5 * time.Minute, 15 * time.Minute}},
+		{"stats_windows=", nil},
+		{"stats_windows=5m,30,5m", []time.Duration{30 * time.Second, 5 * time.Minute}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			config, err := url.ParseQuery(test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			windows, err := getStatsWindows(&config)
+			if err != nil {
+				t.Fatalf("getStatsWindows() error = %v", err)
+			}
+			if len(windows) != len(test.windows) {
+				t.Fatalf("getStatsWindows() = %v, want %v", windows, test.windows)
+			}
+			for index := range windows {
+				if windows[index] != test.windows[index] {
+					t.Errorf("getStatsWindows() = %v, want %v", windows, test.windows)
+				}
+			}
+			if rolling := newProberTaskRolling(windows); (rolling == nil) != (test.windows == nil) {
+				t.Errorf("newProberTaskRolling(%v) = %v", windows, rolling)
+			}
+		})
+	}
+}
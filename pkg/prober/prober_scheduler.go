@@ -0,0 +1,203 @@
+package prober
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-toolbelt/jitter"
+)
+
+type (
+	// proberSchedule plans probes on fixed slots relative to the first one,
+	// so that neither jitter nor slow probes accumulate into drift.
+	proberSchedule struct {
+		mutex       sync.Mutex
+		interval    time.Duration
+		jitterRatio float64
+		jitter      time.Duration // max random delay applied to each slot
+		start       time.Time
+		slot        uint64 // last slot a probe was started for
+		pending     *time.Time
+		failing     bool          // whether probing at `failure_interval`
+		offset      time.Duration // delay of the first probe
+		skipped     uint64        // slots skipped right before the pending one
+		resumed     bool          // slots skipped on purpose are not missed
+		missed      uint64
+
+		latest *probeTiming
+		// timing of in-flight probes, only kept when probes overlap
+		timings map[uint64]*probeTiming
+	}
+
+	// probeTiming describes when a probe was planned and when it actually started
+	probeTiming struct {
+		Planned time.Time
+		Started time.Time
+		Skipped uint64 // slots missed since the previous probe
+	}
+
+	intervalChange struct {
+		From        time.Duration
+		To          time.Duration
+		Failing     bool
+		Consecutive uint64 // failures or successes that triggered the change
+	}
+)
+
+func newProberSchedule(interval time.Duration, jitterRatio float64, offset time.Duration, overlap bool) *proberSchedule {
+	schedule := &proberSchedule{
+		interval:    interval,
+		jitterRatio: jitterRatio,
+		jitter:      time.Duration(float64(interval) * jitterRatio),
+		offset:      offset,
+		latest:      &probeTiming{},
+	}
+	if overlap {
+		schedule.timings = make(map[uint64]*probeTiming)
+	}
+	return schedule
+}
+
+// next provides the planned start time of the upcoming probe: slots that already
+// passed ( i/e: because the previous probe took longer than the interval ) are skipped.
+// A slot only passes once its jitter window does: probes planned late within their slot
+// may end after the next slot begins without delaying any probe beyond its own window.
+func (s *proberSchedule) next(now time.Time) time.Time {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.pending != nil {
+		return *s.pending
+	}
+
+	if s.start.IsZero() {
+		// slot 0 is one interval before the first probe
+		s.start = now.Add(s.offset - s.interval)
+	}
+
+	slot := s.slot + 1
+	if elapsed := now.Sub(s.start); elapsed > time.Duration(slot)*s.interval+s.jitter {
+		// the first slot whose jitter window did not pass yet
+		slot = uint64((elapsed - s.jitter + s.interval - 1) / s.interval)
+	}
+	s.skipped = 0
+	if !s.resumed {
+		s.skipped = slot - s.slot - 1
+		s.missed += s.skipped
+	}
+	s.slot, s.resumed = slot, false
+
+	planned := s.start.Add(time.Duration(slot) * s.interval)
+	if s.jitter > 0 {
+		planned = planned.Add(jitter.UpTo(s.jitter))
+	}
+	if planned.Before(now) {
+		planned = now // the slot began already, but its jitter window is still open
+	}
+	s.pending = &planned
+
+	return planned
+}
+
+// reschedule discards the upcoming probe: the next one is planned on the first slot after now
+func (s *proberSchedule) reschedule() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.pending = nil
+	s.resumed = true
+}
+
+// miss discards the upcoming probe as it could not be sent on time
+func (s *proberSchedule) miss() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.pending = nil
+	s.missed += 1
+}
+
+// started records that the upcoming probe, identified by `attempt`, began at `now`
+func (s *proberSchedule) started(attempt uint64, now time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	timing := &probeTiming{Started: now, Skipped: s.skipped}
+	if s.pending != nil {
+		timing.Planned = *s.pending
+	}
+	s.latest = timing
+	if s.timings != nil {
+		s.timings[attempt] = timing
+	}
+	s.pending = nil
+	s.skipped = 0
+}
+
+// timing provides when the probe identified by `attempt` was planned and started;
+// probes that are not sent by the schedule itself are attributed to the latest one.
+func (s *proberSchedule) timing(attempt uint64) *probeTiming {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if timing, ok := s.timings[attempt]; ok {
+		delete(s.timings, attempt)
+		return timing
+	}
+	return s.latest
+}
+
+// missedProbes provides how many slots passed without a probe being sent
+func (s *proberSchedule) missedProbes() uint64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.missed
+}
+
+// setInterval changes how often probes are planned from the last slot onwards;
+// it must not be called while a probe is pending, i/e: only while probing.
+func (s *proberSchedule) setInterval(interval time.Duration, failing bool) (previous time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	previous = s.interval
+	s.failing = failing
+
+	if !s.start.IsZero() {
+		s.start = s.start.Add(time.Duration(s.slot) * s.interval)
+		s.slot = 0
+	}
+	s.interval = interval
+	s.jitter = time.Duration(float64(interval) * s.jitterRatio)
+
+	return previous
+}
+
+func (s *proberSchedule) isFailing() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.failing
+}
+
+// adaptInterval probes at `failure_interval` after `failure_threshold` consecutive failures,
+// and back at `probe_interval` after `recovery_threshold` consecutive successes.
+func (pt *proberTask) adaptInterval(attempt *uint64) {
+	params := pt.Params
+	if params.FailureInterval == 0 {
+		return
+	}
+
+	stats := pt.Stats
+	change := &intervalChange{}
+
+	switch failing := pt.Schedule.isFailing(); {
+	case !failing && stats.ConsecutiveFailures == params.FailureThreshold:
+		change.To, change.Failing, change.Consecutive = params.FailureInterval, true, stats.ConsecutiveFailures
+	case failing && stats.ConsecutiveSuccesful == params.RecoveryThreshold:
+		change.To, change.Failing, change.Consecutive = params.Interval, false, stats.ConsecutiveSuccesful
+	default:
+		return
+	}
+
+	change.From = pt.Schedule.setInterval(change.To, change.Failing)
+
+	(*pt.Printer).printIntervalChange(pt, attempt, change)
+}
@@ -0,0 +1,72 @@
+package prober
+
+import (
+	"testing"
+	"time"
+)
+
+// the first slot begins when probing starts, as the schedule has no offset
+var scheduleStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// runSchedule simulates `probes` serial probes taking `duration` each, and provides the start time of each one
+func runSchedule(schedule *proberSchedule, probes int, duration time.Duration) []time.Time {
+	now := scheduleStart
+	starts := make([]time.Time, probes)
+	for index := range starts {
+		planned := schedule.next(now)
+		if planned.After(now) {
+			now = planned
+		}
+		starts[index] = now
+		schedule.started(uint64(index+1), now)
+		now = now.Add(duration)
+	}
+	return starts
+}
+
+func TestProberScheduleMissedSlots(t *testing.T) {
+	tests := []struct {
+		name        string
+		interval    time.Duration
+		jitterRatio float64
+		duration    time.Duration
+		probes      int
+		missed      uint64
+	}{
+		{"short probes", time.Second, 0, 300 * time.Millisecond, 100, 0},
+		{"probes as long as the interval", time.Second, 0, time.Second, 100, 0},
+		// every probe ends 2.5 slots after it started: the 2 slots that began meanwhile are missed
+		{"overrunning probes", time.Second, 0, 2500 * time.Millisecond, 100, 2 * 99},
+		// probes planned late within their slot end after the next slot begins, but within its jitter window
+		{"jittered short probes", time.Second, defaultProbeJitter, 300 * time.Millisecond, 10_000, 0},
+		{"fully jittered probes", time.Second, 1.0, 900 * time.Millisecond, 10_000, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schedule := newProberSchedule(test.interval, test.jitterRatio, 0, false)
+			runSchedule(schedule, test.probes, test.duration)
+
+			if got := schedule.missedProbes(); got != test.missed {
+				t.Errorf("missedProbes() = %d, want %d", got, test.missed)
+			}
+		})
+	}
+}
+
+func TestProberSchedulePlannedWithinSlot(t *testing.T) {
+	interval := time.Second
+	schedule := newProberSchedule(interval, defaultProbeJitter, 0, false)
+	jitterWindow := time.Duration(float64(interval) * defaultProbeJitter)
+
+	starts := runSchedule(schedule, 10_000, 300*time.Millisecond)
+
+	// without missed slots, probe `n` starts within the jitter window of slot `n`
+	for index, start := range starts {
+		slotStart := scheduleStart.Add(time.Duration(index) * interval)
+		if start.Before(slotStart) || start.After(slotStart.Add(jitterWindow)) {
+			t.Fatalf("probe %d started at %s, want within [%s, %s]", index+1,
+				start.Sub(scheduleStart), slotStart.Sub(scheduleStart), slotStart.Add(jitterWindow).Sub(scheduleStart))
+		}
+	}
+}
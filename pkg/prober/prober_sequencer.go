@@ -0,0 +1,44 @@
+package prober
+
+import (
+	"sync"
+)
+
+// probeSequencer emits the outcome of overlapping probes in the order they were sent:
+// a probe completing early waits for all the ones sent before it.
+type probeSequencer struct {
+	mutex   sync.Mutex
+	next    uint64
+	pending map[uint64]func()
+}
+
+func newProbeSequencer() *probeSequencer {
+	return &probeSequencer{next: 1, pending: make(map[uint64]func())}
+}
+
+// emit runs `print` once all probes before `attempt` were emitted; a `nil` print
+// only releases the probes after `attempt`. Without sequencer, `print` runs right away.
+func (s *probeSequencer) emit(attempt uint64, print func()) {
+	if s == nil {
+		if print != nil {
+			print()
+		}
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.pending[attempt] = print
+	for {
+		next, ok := s.pending[s.next]
+		if !ok {
+			return
+		}
+		delete(s.pending, s.next)
+		s.next += 1
+		if next != nil {
+			next()
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package prober
+
+import (
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type (
+	// latencySketch estimates quantiles of latencies with a bounded relative error:
+	// latencies are counted in logarithmic buckets, so memory depends on the range
+	// of latencies observed and not on how many were observed. Sketches are mergeable:
+	// merging two sketches is the same as observing all latencies in a single one.
+	latencySketch struct {
+		buckets map[int]uint64
+		zeros   uint64 // latencies too small to be bucketed
+		count   uint64
+	}
+
+	// windowed and lifetime quantiles of a task's latencies; like `latency.min/max/avg`, failures
+	// account for how long they took: refused connections are fast, timed out ones take `probe_timeout`.
+	proberTaskSketch struct {
+		Window  *latencySketch // last `log_size` probes, the same window as `latency.min/max/avg`
+		Overall *latencySketch // all probes up to when stats were last printed
+
+		pending *latencySketch // since stats were last printed: merged into `Overall` when printing them
+	}
+)
+
+const (
+	// estimated quantiles are within 1% of the actual latency
+	sketchRelativeAccuracy = 0.01
+	// smallest latency to be bucketed ( Milliseconds )
+	sketchMinLatency = 0.001
+)
+
+var (
+	sketchGamma    = (1 + sketchRelativeAccuracy) / (1 - sketchRelativeAccuracy)
+	sketchLogGamma = math.Log(sketchGamma)
+)
+
+var defaultQuantiles = []float64{0.5, 0.9, 0.99, 0.999}
+
+func newLatencySketch() *latencySketch {
+	return &latencySketch{buckets: make(map[int]uint64)}
+}
+
+func (s *latencySketch) add(rtt float64) {
+	s.count += 1
+	if rtt < sketchMinLatency {
+		s.zeros += 1
+		return
+	}
+	s.buckets[int(math.Ceil(math.Log(rtt)/sketchLogGamma))] += 1
+}
+
+func (s *latencySketch) merge(other *latencySketch) {
+	for bucket, count := range other.buckets {
+		s.buckets[bucket] += count
+	}
+	s.zeros += other.zeros
+	s.count += other.count
+}
+
+func (s *latencySketch) reset() {
+	clear(s.buckets)
+	s.zeros = 0
+	s.count = 0
+}
+
+// quantile estimates the latency below which a ratio `q` of latencies fall
+func (s *latencySketch) quantile(q float64) (float64, bool) {
+	if s.count == 0 {
+		return 0.0, false
+	}
+
+	// nearest rank: the smallest latency that is greater than or equal to a ratio `q` of them
+	rank := uint64(math.Ceil(q*float64(s.count))) - 1
+	if rank < s.zeros {
+		return 0.0, true
+	}
+
+	buckets := make([]int, 0, len(s.buckets))
+	for bucket := range s.buckets {
+		buckets = append(buckets, bucket)
+	}
+	slices.Sort(buckets)
+
+	seen := s.zeros
+	for _, bucket := range buckets {
+		seen += s.buckets[bucket]
+		if seen > rank {
+			// the bucket's midpoint in relative terms: ( gamma^(i-1), gamma^i ]
+			return 2 * math.Pow(sketchGamma, float64(bucket)) / (sketchGamma + 1), true
+		}
+	}
+	return 2 * math.Pow(sketchGamma, float64(buckets[len(buckets)-1])) / (sketchGamma + 1), true
+}
+
+func newProberTaskSketch() *proberTaskSketch {
+	return &proberTaskSketch{
+		Window:  newLatencySketch(),
+		Overall: newLatencySketch(),
+		pending: newLatencySketch(),
+	}
+}
+
+func (s *proberTaskSketch) observe(rtt float64) {
+	s.pending.add(rtt)
+}
+
+// fold accounts the probes since stats were last printed in the lifetime sketch
+func (s *proberTaskSketch) fold() {
+	s.Overall.merge(s.pending)
+	s.pending.reset()
+}
+
+// window replaces the windowed sketch with the latencies of the last `log_size` probes
+func (s *proberTaskSketch) window(latencies []float64) {
+	s.Window.reset()
+	for _, latency := range latencies {
+		s.Window.add(latency)
+	}
+}
+
+// quantileName renders `q` as a percentile, i/e: `0.999` as `p999` and `0.5` as `p50`;
+// it is built from `q`'s own decimal digits, as `q*100` is not exact ( i/e: `0.57*100` ).
+func quantileName(q float64) string {
+	digits := strings.TrimPrefix(strconv.FormatFloat(q, 'f', -1, 64), "0.")
+	if len(digits) < 2 {
+		digits += "0"
+	}
+	return "p" + digits
+}
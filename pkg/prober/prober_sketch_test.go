@@ -0,0 +1,28 @@
+package prober
+
+import (
+	"testing"
+)
+
+func TestQuantileName(t *testing.T) {
+	tests := []struct {
+		quantile float64
+		want     string
+	}{
+		{0.5, "p50"},
+		{0.9, "p90"},
+		{0.99, "p99"},
+		{0.999, "p999"},
+		{0.05, "p05"},
+		{0.07, "p07"},
+		{0.29, "p29"},
+		{0.57, "p57"},
+		{0.001, "p001"},
+	}
+
+	for _, test := range tests {
+		if got := quantileName(test.quantile); got != test.want {
+			t.Errorf("quantileName(%v) = %q, want %q", test.quantile, got, test.want)
+		}
+	}
+}
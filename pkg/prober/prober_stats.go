@@ -0,0 +1,98 @@
+package prober
+
+import (
+	"math"
+
+	"gonum.org/v1/gonum/stat"
+)
+
+type (
+	latencySample struct {
+		RTT float64 // Milliseconds
+		OK  bool
+	}
+
+	// latencyRing keeps the latest `log_size` samples: once full, new samples overwrite the oldest ones
+	latencyRing struct {
+		samples []latencySample
+		next    int
+		full    bool
+	}
+
+	// latencyStats summarizes a window of latencies ( Milliseconds )
+	latencyStats struct {
+		Count             uint64
+		Min               float64
+		Max               float64
+		Average           float64
+		StandardDeviation float64
+		Skewness          float64
+	}
+)
+
+func newLatencyRing(size int) *latencyRing {
+	return &latencyRing{samples: make([]latencySample, size)}
+}
+
+func (r *latencyRing) add(rtt float64, ok bool) {
+	r.samples[r.next] = latencySample{rtt, ok}
+	r.next += 1
+	if r.next == len(r.samples) {
+		r.next = 0
+		r.full = true
+	}
+}
+
+func (r *latencyRing) len() int {
+	if r.full {
+		return len(r.samples)
+	}
+	return r.next
+}
+
+// latencies provides the samples' latencies from the oldest to the newest; failed probes are skipped if `successful`
+func (r *latencyRing) latencies(successful bool) []float64 {
+	latencies := make([]float64, 0, r.len())
+	start := 0
+	if r.full {
+		start = r.next
+	}
+	for index := range r.len() {
+		sample := r.samples[(start+index)%len(r.samples)]
+		if successful && !sample.OK {
+			continue
+		}
+		latencies = append(latencies, sample.RTT)
+	}
+	return latencies
+}
+
+// finite replaces `NaN` ( i/e: the skewness of identical latencies ) with 0: it cannot be rendered as JSON
+func finite(value float64) float64 {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0.0
+	}
+	return value
+}
+
+func newLatencyStats(latencies []float64) latencyStats {
+	stats := latencyStats{Count: uint64(len(latencies))}
+	if stats.Count == 0 {
+		return stats
+	}
+
+	stats.Min, stats.Max = latencies[0], latencies[0]
+	for _, latency := range latencies[1:] {
+		stats.Min = min(stats.Min, latency)
+		stats.Max = max(stats.Max, latency)
+	}
+	stats.Average = stat.Mean(latencies, nil)
+
+	// spread and asymmetry require at least 2 latencies
+	if stats.Count > 1 {
+		stats.StandardDeviation = finite(stat.StdDev(latencies, nil))
+		stats.Skewness = finite(stat.Skew(latencies, nil))
+	}
+
+	return stats
+}
@@ -0,0 +1,156 @@
+package prober
+
+import (
+	"math"
+	"slices"
+	"testing"
+
+	"gonum.org/v1/gonum/stat"
+)
+
+const statsTolerance = 1e-9
+
+func newRingWith(size int, samples ...latencySample) *latencyRing {
+	ring := newLatencyRing(size)
+	for _, sample := range samples {
+		ring.add(sample.RTT, sample.OK)
+	}
+	return ring
+}
+
+func successes(rtts ...float64) []latencySample {
+	samples := make([]latencySample, len(rtts))
+	for index, rtt := range rtts {
+		samples[index] = latencySample{rtt, true}
+	}
+	return samples
+}
+
+func TestLatencyRing(t *testing.T) {
+	tests := []struct {
+		name       string
+		size       int
+		samples    []latencySample
+		all        []float64
+		successful []float64
+	}{
+		{"empty", 3, nil, []float64{}, []float64{}},
+		{"partial", 3, successes(1, 2), []float64{1, 2}, []float64{1, 2}},
+		{"full", 3, successes(1, 2, 3), []float64{1, 2, 3}, []float64{1, 2, 3}},
+		{"wrapped once", 3, successes(1, 2, 3, 4), []float64{2, 3, 4}, []float64{2, 3, 4}},
+		{"wrapped twice", 3, successes(1, 2, 3, 4, 5, 6, 7), []float64{5, 6, 7}, []float64{5, 6, 7}},
+		{
+			"failures are skipped", 4,
+			[]latencySample{{1, true}, {5000, false}, {3, true}},
+			[]float64{1, 5000, 3}, []float64{1, 3},
+		},
+		{
+			"failures are skipped after wrapping", 3,
+			[]latencySample{{5000, false}, {1, true}, {5000, false}, {2, true}, {3, true}},
+			[]float64{5000, 2, 3}, []float64{2, 3},
+		},
+		{
+			"only failures", 2,
+			[]latencySample{{5000, false}, {5000, false}, {5000, false}},
+			[]float64{5000, 5000}, []float64{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ring := newRingWith(test.size, test.samples...)
+
+			if got := ring.len(); got != len(test.all) {
+				t.Errorf("len() = %d, want %d", got, len(test.all))
+			}
+			if got := ring.latencies(false); !slices.Equal(got, test.all) {
+				t.Errorf("latencies(false) = %v, want %v", got, test.all)
+			}
+			if got := ring.latencies(true); !slices.Equal(got, test.successful) {
+				t.Errorf("latencies(true) = %v, want %v", got, test.successful)
+			}
+		})
+	}
+}
+
+func TestNewLatencyStats(t *testing.T) {
+	tests := []struct {
+		name      string
+		latencies []float64
+		want      latencyStats
+	}{
+		{"no latencies", nil, latencyStats{}},
+		{"one latency", []float64{5}, latencyStats{Count: 1, Min: 5, Max: 5, Average: 5}},
+		{"identical latencies", []float64{300, 300, 300}, latencyStats{Count: 3, Min: 300, Max: 300, Average: 300}},
+		{"symmetric latencies", []float64{1, 2, 3, 4}, latencyStats{
+			Count: 4, Min: 1, Max: 4, Average: 2.5,
+			// sqrt( ( 1.5² + 0.5² + 0.5² + 1.5² ) / 3 )
+			StandardDeviation: math.Sqrt(5.0 / 3.0),
+		}},
+		{"skewed latencies", []float64{10, 1, 3, 2}, latencyStats{
+			Count: 4, Min: 1, Max: 10, Average: 4,
+			// deviations: 6, -3, -1, -2 => sqrt( 50 / 3 )
+			StandardDeviation: math.Sqrt(50.0 / 3.0),
+			// sum( z³ ) * n / ( ( n - 1 ) * ( n - 2 ) ) => ( 180 / σ³ ) * 4 / 6
+			Skewness: 180.0 / math.Pow(50.0/3.0, 1.5) * 4.0 / 6.0,
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := newLatencyStats(test.latencies)
+			want := test.want
+
+			if got.Count != want.Count {
+				t.Errorf("Count = %d, want %d", got.Count, want.Count)
+			}
+			for _, field := range []struct {
+				name      string
+				got, want float64
+			}{
+				{"Min", got.Min, want.Min},
+				{"Max", got.Max, want.Max},
+				{"Average", got.Average, want.Average},
+				{"StandardDeviation", got.StandardDeviation, want.StandardDeviation},
+				{"Skewness", got.Skewness, want.Skewness},
+			} {
+				if math.IsNaN(field.got) || math.Abs(field.got-field.want) > statsTolerance {
+					t.Errorf("%s = %v, want %v", field.name, field.got, field.want)
+				}
+			}
+		})
+	}
+}
+
+// the window used to be fed every latency twice, which shrinks the standard deviation
+func TestLatencyStatsAccountEachSampleOnce(t *testing.T) {
+	tests := []struct {
+		name      string
+		size      int
+		latencies []float64
+	}{
+		{"partial ring", 8, []float64{1, 2, 3, 10}},
+		{"full ring", 4, []float64{1, 2, 3, 10}},
+		{"wrapped ring", 4, []float64{100, 200, 1, 2, 3, 10}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ring := newRingWith(test.size, successes(test.latencies...)...)
+
+			window := test.latencies[max(len(test.latencies)-test.size, 0):]
+			latencies := ring.latencies(false)
+			if len(latencies) != len(window) {
+				t.Fatalf("latencies(false) has %d samples, want %d", len(latencies), len(window))
+			}
+
+			got := newLatencyStats(latencies)
+			if want := stat.StdDev(window, nil); math.Abs(got.StandardDeviation-want) > statsTolerance {
+				t.Errorf("StandardDeviation = %v, want %v", got.StandardDeviation, want)
+			}
+			if want := stat.Mean(window, nil); math.Abs(got.Average-want) > statsTolerance {
+				t.Errorf("Average = %v, want %v", got.Average, want)
+			}
+		})
+	}
+}
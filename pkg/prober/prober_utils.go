@@ -2,12 +2,15 @@ package prober
 
 import (
 	"context"
+	"errors"
+	"io"
 	"math/rand"
 	"net"
 	"net/netip"
 	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/lainio/err2"
@@ -112,8 +115,11 @@ func resolveHostnameToIPv6(taskURL *url.URL) (IPv4 netip.Addr, err error) {
 	return try.To1(selectIPv6(IPv4s)), err
 }
 
-func getProberTaskPort(taskURL *url.URL) (port int, err error) {
+func getProberTaskPort(taskType ProberType, taskURL *url.URL) (port int, err error) {
 	defer err2.Handle(&err, "getProberTaskPort")
+	if taskType == UNIX {
+		return 0, nil
+	}
 	return try.To1(strconv.Atoi(taskURL.Port())), err
 }
 
@@ -122,6 +128,10 @@ func getProberTaskIP(taskType ProberType, taskURL *url.URL) (IP netip.Addr, err
 	switch taskType {
 	default:
 		IP = try.To1(netip.ParseAddr(taskURL.Hostname()))
+	case UNIX:
+		if taskURL.Path == "" {
+			return IP, errorx.WithMessage(errorUnknownHostname, "socket path is required")
+		}
 	case DNS_IPv4, HTTP_IPv4, HTTPS_IPv4:
 		IP = try.To1(resolveHostnameToIPv4(taskURL))
 	case DNS_IPv6, HTTP_IPv6, HTTPS_IPv6:
@@ -142,5 +152,152 @@ func getProberTaskType(taskURL *url.URL) (ProberType, error) {
 		return DNS_IPv4, nil
 	case DNS_IPv6_SCHEME:
 		return DNS_IPv6, nil
+	case UNIX_SCHEME:
+		return UNIX, nil
+	}
+}
+
+// newLocalTCPAddr provides the address probes should be sent from,
+// or `nil` to let the kernel pick both the source IP and port
+func newLocalTCPAddr(params *proberTaskParams, port uint16) net.Addr {
+	if !params.SourceIP.IsValid() && port == 0 {
+		return nil
+	}
+	addr := &net.TCPAddr{Port: int(port)}
+	if params.SourceIP.IsValid() {
+		addr.IP = params.SourceIP.AsSlice()
+	}
+	return addr
+}
+
+// exchangePayload sends the task's payload over `conn` and verifies that the response
+// contains the expected content; it is a no-op when no payload is configured.
+func exchangePayload(conn net.Conn, params *proberTaskParams, deadline time.Time) error {
+	if params.Payload == "" {
+		return nil
+	}
+
+	conn.SetDeadline(deadline)
+
+	if _, err := io.WriteString(conn, params.Payload); err != nil {
+		return err
+	}
+
+	response := make([]byte, 4096)
+	n, err := conn.Read(response)
+	if err != nil && !(errors.Is(err, io.EOF) && n > 0) {
+		return err
+	}
+
+	if params.Expect != "" && !strings.Contains(string(response[:n]), params.Expect) {
+		return errorx.WithMessage(errorUnexpectedResponse, strconv.Quote(string(response[:n])))
+	}
+
+	return nil
+}
+
+// splitTaskURL isolates the hosts and ports sections of the task URL's authority, which `url.Parse`
+// rejects when they describe several targets: i/e: `ipv4://10.8.0.0/28:80,443?{params}`
+func splitTaskURL(rawTaskURL string) (head, hosts, ports, tail string) {
+	start := strings.Index(rawTaskURL, "://")
+	if start < 0 {
+		return rawTaskURL, "", "", ""
+	}
+	start += len("://")
+
+	// only IP addresses may be followed by a prefix length, other schemes may have a path
+	terminators := "/?#"
+	if scheme := rawTaskURL[:start]; scheme == RAW_IPv4_SCHEME+"://" || scheme == RAW_IPv6_SCHEME+"://" {
+		terminators = "?#"
+	}
+
+	end := len(rawTaskURL)
+	if index := strings.IndexAny(rawTaskURL[start:], terminators); index >= 0 {
+		end = start + index
+	}
+	authority := rawTaskURL[start:end]
+
+	// IPv6 literals are enclosed in brackets: `[::1]:80,443`
+	separator := strings.LastIndex(authority, ":")
+	if separator < 0 || separator < strings.LastIndex(authority, "]") {
+		return rawTaskURL[:start], authority, "", rawTaskURL[end:]
+	}
+
+	return rawTaskURL[:start], authority[:separator], authority[separator+1:], rawTaskURL[end:]
+}
+
+// getProberTaskAddrs expands a CIDR block ( i/e: `10.8.0.0/28` ) or a range of addresses
+// ( i/e: `10.8.0.1-10.8.0.9` ) into the addresses it describes; `nil` when hosts is a single one.
+func getProberTaskAddrs(taskType ProberType, hosts string, limit uint32) (addrs []netip.Addr, err error) {
+	defer err2.Handle(&err, "getProberTaskAddrs")
+
+	if taskType != RAW_IPv4 && taskType != RAW_IPv6 {
+		return nil, nil
+	}
+
+	hosts = strings.NewReplacer("[", "", "]", "").Replace(hosts)
+
+	var first, last netip.Addr
+	if strings.Contains(hosts, "/") {
+		prefix := try.To1(netip.ParsePrefix(hosts)).Masked()
+		first = prefix.Addr()
+		last = lastAddr(prefix)
+		// the network and broadcast addresses of IPv4 subnets are not hosts
+		if first.Is4() && prefix.Bits() < 31 {
+			first, last = first.Next(), last.Prev()
+		}
+	} else if from, to, isRange := strings.Cut(hosts, "-"); isRange {
+		first = try.To1(netip.ParseAddr(from))
+		last = try.To1(netip.ParseAddr(to))
+	} else {
+		return nil, nil
+	}
+
+	if first.Is4() != (taskType == RAW_IPv4) || last.Is4() != first.Is4() || last.Less(first) {
+		return nil, errorx.WithMessage(errorInvalidAddrs, hosts)
+	}
+
+	for addr := first; addr.IsValid() && !last.Less(addr); addr = addr.Next() {
+		if uint32(len(addrs)) == limit {
+			return nil, errorx.WithMessagef(errorTooManyTargets, "%s: more than %d addresses", hosts, limit)
+		}
+		addrs = append(addrs, addr)
+	}
+	return addrs, nil
+}
+
+// lastAddr provides the highest address within the prefix
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	bytes := prefix.Addr().AsSlice()
+	for bit := prefix.Bits(); bit < len(bytes)*8; bit++ {
+		bytes[bit/8] |= 0x80 >> (bit % 8)
+	}
+	addr, _ := netip.AddrFromSlice(bytes)
+	return addr
+}
+
+// getProberTaskPorts expands a list of ports and ranges ( i/e: `80,443,8000-8010` )
+// into the ports it describes, in order and without duplicates.
+func getProberTaskPorts(spec string) (ports []uint16, err error) {
+	defer err2.Handle(&err, "getProberTaskPorts")
+
+	seen := make(map[uint16]bool)
+	for _, item := range strings.Split(spec, ",") {
+		first, last, isRange := strings.Cut(strings.TrimSpace(item), "-")
+		if !isRange {
+			last = first
+		}
+		firstPort := try.To1(strconv.ParseUint(first, 10, 16))
+		lastPort := try.To1(strconv.ParseUint(last, 10, 16))
+		if firstPort == 0 || lastPort < firstPort {
+			return nil, errorx.WithMessage(errorInvalidPorts, item)
+		}
+		for port := firstPort; port <= lastPort; port++ {
+			if !seen[uint16(port)] {
+				seen[uint16(port)] = true
+				ports = append(ports, uint16(port))
+			}
+		}
 	}
+	return ports, nil
 }
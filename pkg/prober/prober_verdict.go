@@ -0,0 +1,65 @@
+package prober
+
+type (
+	verdictCriterion struct {
+		Name      string
+		Threshold float64
+		Value     float64
+		Passed    bool
+	}
+
+	// proberVerdict tells whether a task met all of its success criteria once probing is over
+	proberVerdict struct {
+		Passed   bool
+		Criteria []*verdictCriterion
+	}
+)
+
+func (v *proberVerdict) check(name string, threshold, value float64, passed bool) {
+	v.Criteria = append(v.Criteria, &verdictCriterion{name, threshold, value, passed})
+	v.Passed = v.Passed && passed
+}
+
+// verdict evaluates the task's success criteria and prints the outcome;
+// it is `nil` when no criteria were configured.
+func (pt *proberTask) verdict() *proberVerdict {
+	params := pt.Params
+	stats := pt.Stats
+
+	verdict := &proberVerdict{Passed: true}
+
+	if params.MinSuccessRatio > 0 {
+		ratio := 0.0
+		if stats.TotalProbes > 0 {
+			ratio = float64(stats.TotalSuccessful) / float64(stats.TotalProbes)
+		}
+		verdict.check(PARAM_MIN_SUCCESS_RATIO, params.MinSuccessRatio, ratio, ratio >= params.MinSuccessRatio)
+	}
+
+	if params.MaxP99 > 0 {
+		// all probes count: the lifetime sketch was folded when the final stats were printed
+		// discoveries ( `pmtu` and `idle_timeout` probes ) observe no latencies to meet it with
+		p99, observed := pt.Sketch.Overall.quantile(0.99)
+		maxP99 := asMillis(&params.MaxP99)
+		verdict.check(PARAM_MAX_P99, maxP99, p99, observed && stats.TotalSuccessful > 0 && p99 <= maxP99)
+	}
+
+	if maxFailures := params.MaxConsecutiveFailures; maxFailures != nil {
+		failures := stats.MaxConsecutiveFailures
+		verdict.check(PARAM_MAX_CONSECUTIVE_FAILURES, float64(*maxFailures), float64(failures), failures <= *maxFailures)
+	}
+
+	if len(verdict.Criteria) == 0 {
+		return nil
+	}
+
+	(*pt.Printer).printVerdict(pt, verdict)
+
+	return verdict
+}
+
+// Verdict reports whether the prober met all of its success criteria; it must be called once probing is over.
+func Verdict(prober *Prober) bool {
+	verdict := (*prober).verdict()
+	return verdict == nil || verdict.Passed
+}
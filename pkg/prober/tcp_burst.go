@@ -0,0 +1,169 @@
+package prober
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+
+	errorx "github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+	"gonum.org/v1/gonum/stat"
+)
+
+type (
+	burstResult struct {
+		Size       uint16
+		Successful uint16
+		Failed     uint16
+		// latencies of successful connections ( Milliseconds )
+		MinLatency    float64
+		MaxLatency    float64
+		AvgLatency    float64
+		MedianLatency float64
+		P90Latency    float64
+		Errors        map[string]uint16
+	}
+)
+
+const (
+	BURST_ERROR_TIMEOUT  = "timeout"
+	BURST_ERROR_CANCELED = "canceled"
+)
+
+var errorBurstFailures = errorx.New("burst connections failed")
+
+// burstErrorName groups connection errors by their cause: `EADDRNOTAVAIL` typically
+// signals that no more source ports ( i/e: NAT allocations ) are available.
+func burstErrorName(err error) string {
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		if name := unix.ErrnoName(errno); name != "" {
+			return name
+		}
+		return errno.Error()
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return BURST_ERROR_TIMEOUT
+	}
+	if errors.Is(err, context.Canceled) {
+		return BURST_ERROR_CANCELED
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return BURST_ERROR_TIMEOUT
+	}
+	return err.Error()
+}
+
+func newBurstResult(size uint16, latencies []float64, errs []error) *burstResult {
+	result := &burstResult{
+		Size:   size,
+		Errors: make(map[string]uint16),
+	}
+
+	for _, err := range errs {
+		if err != nil {
+			result.Failed += 1
+			result.Errors[burstErrorName(err)] += 1
+		}
+	}
+	result.Successful = size - result.Failed
+
+	if len(latencies) == 0 {
+		return result
+	}
+
+	slices.Sort(latencies)
+	result.MinLatency = latencies[0]
+	result.MaxLatency = latencies[len(latencies)-1]
+	result.AvgLatency = stat.Mean(latencies, nil)
+	result.MedianLatency = stat.Quantile(0.5, stat.Empirical, latencies, nil)
+	result.P90Latency = stat.Quantile(0.9, stat.Empirical, latencies, nil)
+
+	return result
+}
+
+// holdConnections keeps all burst connections open for `burst_hold`, then closes them;
+// it runs in the background, so that holding a burst does not delay the next probes.
+func (p *TCPProberTask) holdConnections(ctx context.Context, conns []net.Conn) {
+	defer p.holding.Done()
+
+	if hold := p.Params.BurstHold; hold > 0 {
+		timer := time.NewTimer(hold)
+		select {
+		case <-ctx.Done():
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+
+	for _, conn := range conns {
+		if conn != nil {
+			closeConnection(conn, p.Params.CloseMode, p.Params.Timeout)
+		}
+	}
+}
+
+// probeBurst opens `burst` connections concurrently: limits such as NAT port allocations
+// only show up under concurrency. The probe's latency is the time to establish the whole burst.
+func (p *TCPProberTask) probeBurst(ctx context.Context, attempt *uint64, target *netip.AddrPort) (*time.Duration, error) {
+	size := p.Params.Burst
+
+	if p.Params.SourcePortRange != nil {
+		// fixed source ports cannot be reused while held connections still use them:
+		// connecting from them would fail locally with `EADDRNOTAVAIL`, as if NAT ports were exhausted.
+		p.holding.Wait()
+	}
+
+	conns := make([]net.Conn, size)
+	errs := make([]error, size)
+	latencies := make([]float64, 0, size)
+
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+
+	start := time.Now()
+	for index := range conns {
+		localAddr := p.nextLocalAddr()
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			conn, data, err := p.connectFrom(ctx, target, localAddr)
+			conns[index], errs[index] = conn, err
+			if err == nil {
+				mutex.Lock()
+				latencies = append(latencies, asMillis(data.latency))
+				mutex.Unlock()
+			}
+		}(index)
+	}
+	wg.Wait()
+	latency := time.Since(start)
+
+	result := newBurstResult(size, latencies, errs)
+
+	var err error
+	if result.Failed > 0 {
+		err = errorx.WithMessagef(errorBurstFailures, "%d/%d", result.Failed, result.Size)
+	}
+
+	p.afterProbing(ctx, attempt, target, &proberTaskData{latency: &latency}, err)
+
+	(*p.Printer).printBurst(&p.proberTask, attempt, target, result)
+
+	p.holding.Add(1)
+	go p.holdConnections(ctx, conns)
+
+	return &latency, err
+}
+
+// close waits for held burst connections to be closed, as probing is over
+func (p *TCPProberTask) close() error {
+	p.holding.Wait()
+	return p.proberTask.close()
+}
@@ -0,0 +1,267 @@
+package prober
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+type (
+	idleConnection struct {
+		Idle  time.Duration
+		Alive bool
+		Cause string
+		Err   error
+	}
+
+	idleTimeoutDiscovery struct {
+		// longest idle period survived, and shortest idle period not survived
+		LowerBound time.Duration
+		UpperBound time.Duration
+		Estimate   time.Duration
+		Bounded    bool // at least one connection died within `idle_max`
+		Rounds     uint16
+		Tests      []*idleConnection
+	}
+
+	IdleTimeoutProberTask struct {
+		TCPProberTask
+	}
+)
+
+const (
+	// after idling, TCP keepalives are sent every second and the connection is considered dead after 3 unanswered ones
+	idleKeepAliveInterval = 1
+	idleKeepAliveCount    = 3
+	idleTestTimeout       = (idleKeepAliveInterval*(idleKeepAliveCount+1) + 1) * time.Second
+)
+
+var errorIdleNoConnection = errors.New("no connection could be established")
+
+func newIdleTimeoutProberTask(task *proberTask) Prober {
+	p := newTCPProberTask(task).(*TCPProberTask)
+	// Go enables keepalives by default, which would keep connections from ever going idle:
+	// keepalives are only sent by `testKeepAlive`, once a connection idled for long enough.
+	p.dialer.KeepAlive = -1
+	return &IdleTimeoutProberTask{*p}
+}
+
+// testKeepAlive forces the kernel to verify that the peer still knows about the connection:
+// middleboxes that dropped the connection's state either reset it or silently drop the keepalives.
+func testKeepAlive(conn net.Conn) error {
+	tcpConn, ok := conn.(*net.TCPConn)
+	if !ok {
+		return nil
+	}
+
+	buffer := make([]byte, 512)
+
+	// anything the peer sent while the connection was idle ( FIN or RST ) is reported right away
+	tcpConn.SetReadDeadline(time.Now().Add(livenessCheckTimeout))
+	if _, err := tcpConn.Read(buffer); !errors.Is(err, os.ErrDeadlineExceeded) {
+		return err
+	}
+
+	rawConn, err := tcpConn.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var operr error
+	if err := rawConn.Control(func(fd uintptr) {
+		operr = errors.Join(
+			unix.SetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_KEEPIDLE, idleKeepAliveInterval),
+			unix.SetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_KEEPINTVL, idleKeepAliveInterval),
+			unix.SetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_KEEPCNT, idleKeepAliveCount),
+			unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_KEEPALIVE, 1),
+		)
+	}); err != nil {
+		return err
+	}
+	if operr != nil {
+		return operr
+	}
+
+	tcpConn.SetReadDeadline(time.Now().Add(idleTestTimeout))
+	_, err = tcpConn.Read(buffer)
+	// all keepalives were acknowledged, or the peer closed the connection after idling: the path held
+	if errors.Is(err, os.ErrDeadlineExceeded) || errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
+
+func (p *IdleTimeoutProberTask) testConnection(conn net.Conn) error {
+	if p.Params.Payload != "" {
+		return exchangePayload(conn, p.Params, time.Now().Add(p.Params.Timeout))
+	}
+	return testKeepAlive(conn)
+}
+
+// idleFor keeps `conn` idle for `idle` and then verifies whether it is still usable
+func (p *IdleTimeoutProberTask) idleFor(ctx context.Context, conn net.Conn, idle time.Duration) *idleConnection {
+	test := &idleConnection{Idle: idle}
+
+	timer := time.NewTimer(idle)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		test.Err = ctx.Err()
+		return test
+	case <-timer.C:
+	}
+
+	if err := p.testConnection(conn); err != nil {
+		test.Cause = connectionDeathCause(err)
+		test.Err = err
+		return test
+	}
+
+	test.Alive = true
+	return test
+}
+
+// idlePeriods spreads the connections of a round between the current bounds
+func idlePeriods(low, high time.Duration, connections int, inclusive bool) []time.Duration {
+	periods := make([]time.Duration, connections)
+	steps := time.Duration(connections + 1)
+	offset := time.Duration(1)
+	if inclusive {
+		steps, offset = time.Duration(connections-1), 0
+	}
+	for index := range periods {
+		periods[index] = low + (high-low)*(time.Duration(index)+offset)/steps
+	}
+	return periods
+}
+
+func (p *IdleTimeoutProberTask) runRound(ctx context.Context, target *netip.AddrPort, periods []time.Duration) ([]*idleConnection, error) {
+	tests := make([]*idleConnection, len(periods))
+
+	var wg sync.WaitGroup
+	var connected uint16
+	var mutex sync.Mutex
+	var lastErr error
+
+	for index, idle := range periods {
+		wg.Add(1)
+		go func(index int, idle time.Duration) {
+			defer wg.Done()
+
+			conn, _, err := p.connect(ctx, target)
+			if err != nil {
+				mutex.Lock()
+				lastErr = err
+				mutex.Unlock()
+				return
+			}
+			defer conn.Close()
+
+			mutex.Lock()
+			connected += 1
+			mutex.Unlock()
+
+			tests[index] = p.idleFor(ctx, conn, idle)
+		}(index, idle)
+	}
+	wg.Wait()
+
+	if connected == 0 {
+		return nil, errors.Join(errorIdleNoConnection, lastErr)
+	}
+	return tests, nil
+}
+
+// discover estimates the idle timeout enforced on the path by keeping several connections idle
+// for different periods, and narrowing the range between the longest survived and shortest failed.
+func (p *IdleTimeoutProberTask) discover(ctx context.Context, target *netip.AddrPort) (*idleTimeoutDiscovery, error) {
+	params := p.Params
+	connections := int(params.IdleConnections)
+
+	discovery := &idleTimeoutDiscovery{
+		LowerBound: 0,
+		UpperBound: params.IdleMax,
+	}
+
+	low, high := params.IdleMin, params.IdleMax
+	survived := false
+	inclusive := true // the first round also tests both ends of the range
+
+	for high-low > params.IdleResolution {
+		periods := idlePeriods(low, high, connections, inclusive)
+		inclusive = false
+
+		tests, err := p.runRound(ctx, target, periods)
+		if err != nil {
+			return discovery, err
+		}
+		if ctx.Err() != nil {
+			return discovery, ctx.Err()
+		}
+
+		discovery.Rounds += 1
+
+		for _, test := range tests {
+			if test == nil {
+				continue
+			}
+			discovery.Tests = append(discovery.Tests, test)
+			if test.Alive && test.Idle >= low {
+				low = test.Idle
+				survived = true
+			}
+			if !test.Alive && test.Idle < high {
+				high = test.Idle
+				discovery.Bounded = true
+			}
+		}
+
+		if !discovery.Bounded {
+			// every connection survived `idle_max`: the timeout, if any, is beyond the tested range
+			low = high
+		}
+
+		// connections that died before outliving shorter ones make the range inconsistent
+		if low >= high {
+			break
+		}
+	}
+
+	if !survived {
+		low = 0 // not even `idle_min` was survived
+	}
+
+	discovery.LowerBound = low
+	discovery.UpperBound = high
+	discovery.Estimate = low + (high-low)/2
+	if !discovery.Bounded {
+		discovery.Estimate = high
+	}
+
+	return discovery, nil
+}
+
+func (p *IdleTimeoutProberTask) probe(ctx context.Context, attempt *uint64) (*time.Duration, error) {
+	target, err := p.beforeProbing(ctx, attempt)
+	if err != nil {
+		target = p.Target
+	}
+
+	start := time.Now()
+	discovery, err := p.discover(ctx, target)
+	latency := time.Since(start)
+
+	p.afterProbing(ctx, attempt, target, &proberTaskData{latency: &latency, discovery: true}, err)
+
+	(*p.Printer).printIdleTimeout(&p.proberTask, attempt, target, discovery, err)
+
+	return &latency, err
+}
@@ -0,0 +1,170 @@
+package prober
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"syscall"
+	"time"
+)
+
+type (
+	connectionEvent struct {
+		Serial    uint64
+		LocalAddr string
+		Cause     string
+		Age       time.Duration
+		Checks    uint64
+		Err       error
+	}
+
+	PersistentTCPProberTask struct {
+		TCPProberTask
+		conn        net.Conn
+		target      *netip.AddrPort
+		localAddr   string
+		connectedAt time.Time
+		connections uint64
+		checks      uint64
+	}
+)
+
+const (
+	CONNECTION_DEATH_RST     = "RST"
+	CONNECTION_DEATH_EOF     = "EOF"
+	CONNECTION_DEATH_TIMEOUT = "timeout"
+	CONNECTION_DEATH_ERROR   = "error"
+)
+
+// how long to wait for the kernel to report the state of an idle connection
+const livenessCheckTimeout = 1 * time.Millisecond
+
+func newPersistentTCPProberTask(task *proberTask) Prober {
+	p := newTCPProberTask(task).(*TCPProberTask)
+	if task.Params.Payload != "" {
+		// the `payload` exchange is the liveness check: TCP keepalives are only used without it
+		p.dialer.KeepAlive = -1
+	}
+	return &PersistentTCPProberTask{TCPProberTask: *p}
+}
+
+func connectionDeathCause(err error) string {
+	switch {
+	case errors.Is(err, io.EOF):
+		return CONNECTION_DEATH_EOF
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE):
+		return CONNECTION_DEATH_RST
+	case errors.Is(err, syscall.ETIMEDOUT), errors.Is(err, os.ErrDeadlineExceeded):
+		return CONNECTION_DEATH_TIMEOUT
+	}
+	return CONNECTION_DEATH_ERROR
+}
+
+// checkLiveness verifies that the connection is still usable: with a `payload` an application-level
+// keepalive is exchanged; otherwise, TCP keepalives are relied upon and the kernel is asked
+// whether the connection was terminated ( FIN, RST or keepalive/user timeout ) since the last check.
+func (p *PersistentTCPProberTask) checkLiveness() error {
+	if p.Params.Payload != "" {
+		return exchangePayload(p.conn, p.Params, time.Now().Add(p.Params.Timeout))
+	}
+
+	p.conn.SetReadDeadline(time.Now().Add(livenessCheckTimeout))
+	buffer := make([]byte, 512)
+	_, err := p.conn.Read(buffer)
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return nil // nothing to report: the connection is idle
+	}
+	return err // data sent by the peer is also a sign of liveness
+}
+
+func (p *PersistentTCPProberTask) enableKeepAlive(conn net.Conn) {
+	if p.Params.Payload != "" {
+		return
+	}
+	if tcpConn, ok := conn.(*net.TCPConn); ok {
+		// sets both `TCP_KEEPIDLE` and `TCP_KEEPINTVL`
+		tcpConn.SetKeepAlive(true)
+		tcpConn.SetKeepAlivePeriod(p.Params.KeepAliveInterval)
+	}
+}
+
+func (p *PersistentTCPProberTask) disconnect(err error) {
+	event := &connectionEvent{
+		Serial:    p.connections,
+		LocalAddr: p.localAddr,
+		Cause:     connectionDeathCause(err),
+		Age:       time.Since(p.connectedAt),
+		Checks:    p.checks,
+		Err:       err,
+	}
+	(*p.Printer).printConnectionEvent(&p.proberTask, p.target, event)
+
+	p.conn.Close()
+	p.conn = nil
+	p.checks = 0
+}
+
+func (p *PersistentTCPProberTask) reconnect(ctx context.Context, attempt *uint64, target *netip.AddrPort) (*time.Duration, error) {
+	p.connections += 1
+
+	conn, data, err := p.connect(ctx, target)
+	if err == nil {
+		p.enableKeepAlive(conn)
+		p.conn = conn
+		p.target = target
+		p.localAddr = data.localAddr
+		p.connectedAt = time.Now()
+	}
+	data.connection = p.connections
+
+	p.afterProbing(ctx, attempt, target, data, err)
+
+	return data.latency, err
+}
+
+// check accounts a tick on which the connection is still alive as a successful probe: its latency is
+// the `payload` exchange, or the kernel's smoothed RTT of the connection when relying on TCP keepalives.
+func (p *PersistentTCPProberTask) check(ctx context.Context, attempt *uint64) (*time.Duration, error) {
+	start := time.Now()
+	err := p.checkLiveness()
+	latency := time.Since(start)
+
+	if err != nil {
+		return nil, err
+	}
+	p.checks += 1
+
+	data := &proberTaskData{latency: &latency, localAddr: p.localAddr, connection: p.connections}
+	if p.Params.Payload == "" {
+		if info, err := getTCPInfo(p.conn); err == nil {
+			data.tcpInfo = info
+			latency = time.Duration(info.RTT * float64(time.Millisecond))
+		}
+	}
+
+	p.afterProbing(ctx, attempt, p.target, data, nil)
+
+	return &latency, nil
+}
+
+// probe holds a single connection open: each tick checks if it is still alive,
+// and reconnects when it is not; every tick is accounted as a probe.
+func (p *PersistentTCPProberTask) probe(ctx context.Context, attempt *uint64) (*time.Duration, error) {
+	target, err := p.beforeProbing(ctx, attempt)
+	if err != nil {
+		target = p.Target
+	}
+
+	if p.conn != nil {
+		latency, err := p.check(ctx, attempt)
+		if err == nil {
+			return latency, nil
+		}
+		p.disconnect(err)
+	}
+
+	return p.reconnect(ctx, attempt, target)
+}
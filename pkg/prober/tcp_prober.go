@@ -4,7 +4,11 @@ import (
 	"context"
 	"crypto/tls"
 	"errors"
+	"io"
 	"net"
+	"net/netip"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -12,10 +16,27 @@ import (
 )
 
 type (
+	// kernel's view of a connection: https://man7.org/linux/man-pages/man7/tcp.7.html ( `TCP_INFO` )
+	tcpInfo struct {
+		RTT              float64 // Milliseconds
+		RTTVariance      float64 // Milliseconds
+		SYNRetransmits   uint32
+		MSS              uint32
+		CongestionWindow uint32
+	}
+
+	// a connection whose SYN or SYN+ACK was lost, but succeeded after retransmitting
+	synLoss struct {
+		Retransmits uint32
+		Confirmed   bool // `TCP_INFO` reported the retransmissions
+	}
+
 	TCPProberTask struct {
 		proberTask
-		network string
-		dialer  *net.Dialer
+		network     string
+		dialer      *net.Dialer
+		sourcePorts uint64
+		holding     *sync.WaitGroup // bursts whose connections are still held open
 	}
 )
 
@@ -26,17 +47,79 @@ var (
 	tlsConfig = &tls.Config{InsecureSkipVerify: true}
 )
 
-func setSocketOptions(fd uintptr, err *error) {
+const (
+	// Linux retransmits SYNs after `TCP_TIMEOUT_INIT` and doubles it on each attempt: 1s, 3s, 7s, 15s...
+	// see: https://github.com/torvalds/linux/blob/master/include/net/tcp.h
+	synInitialRTO = 1 * time.Second
+	// how far from the retransmission schedule a connection may complete and still be attributed to it
+	synRetransmitTolerance = 250 * time.Millisecond
+)
+
+func bindToDevice(task *proberTask, fd uintptr) error {
+	if task.Params.BindDevice == "" {
+		return nil
+	}
+	// https://man7.org/linux/man-pages/man7/socket.7.html ( `SO_BINDTODEVICE` )
+	return unix.BindToDevice(int(fd), task.Params.BindDevice)
+}
+
+// setTrafficOptions applies the QoS and policy routing options configured for the task
+func setTrafficOptions(task *proberTask, fd uintptr, IPv6 bool) error {
+	params := task.Params
+
+	var classErr, markErr, priorityErr error
+	if IPv6 && params.TrafficClass != nil {
+		classErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_IPV6, unix.IPV6_TCLASS, int(*params.TrafficClass))
+	} else if !IPv6 && params.TOS != nil {
+		classErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_IP, unix.IP_TOS, int(*params.TOS))
+	}
+	// https://man7.org/linux/man-pages/man7/socket.7.html ( `SO_MARK` and `SO_PRIORITY` )
+	if params.Mark != nil {
+		markErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_MARK, int(*params.Mark))
+	}
+	if params.Priority != nil {
+		priorityErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_PRIORITY, int(*params.Priority))
+	}
+	return errors.Join(classErr, markErr, priorityErr)
+}
+
+// setConnectOptions applies kernel-level tuning of the connection attempt
+func setConnectOptions(task *proberTask, fd uintptr) error {
+	params := task.Params
+
+	var synCountErr, userTimeoutErr error
+	// https://man7.org/linux/man-pages/man7/tcp.7.html ( `TCP_SYNCNT` and `TCP_USER_TIMEOUT` )
+	if params.SYNCount != nil {
+		synCountErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_SYNCNT, int(*params.SYNCount))
+	}
+	if params.UserTimeout != nil {
+		userTimeoutErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_USER_TIMEOUT, int(*params.UserTimeout))
+	}
+	return errors.Join(synCountErr, userTimeoutErr)
+}
+
+func setSocketOptions(task *proberTask, fd uintptr, IPv6 bool, err *error) {
 	// https://golang.google.cn/src/internal/poll/sockopt.go
 	// https://pkg.go.dev/syscall#SetsockoptLinger
-	lingerErr := syscall.SetsockoptLinger(int(fd), unix.SOL_SOCKET, unix.SO_LINGER, _SO_LINGER)
-	*err = errors.Join(lingerErr)
+	var lingerErr error
+	if task.Params.CloseMode == CLOSE_MODE_RST {
+		lingerErr = syscall.SetsockoptLinger(int(fd), unix.SOL_SOCKET, unix.SO_LINGER, _SO_LINGER)
+	}
+	deviceErr := bindToDevice(task, fd)
+	trafficErr := setTrafficOptions(task, fd, IPv6)
+	tuningErr := setConnectOptions(task, fd)
+	var reuseErr error
+	if task.Params.SourcePortRange != nil {
+		// allow fixed source ports to be reused by consecutive probes
+		reuseErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEADDR, 1)
+	}
+	*err = errors.Join(lingerErr, deviceErr, trafficErr, tuningErr, reuseErr)
 }
 
 func connectionControl(task *proberTask, network, address string, conn syscall.RawConn) error {
 	var operr error
 	if err := conn.Control(func(fd uintptr) {
-		setSocketOptions(fd, &operr)
+		setSocketOptions(task, fd, network == "tcp6", &operr)
 	}); err != nil {
 		return err
 	}
@@ -63,28 +146,166 @@ func newTCPProberTask(task *proberTask) Prober {
 		},
 	}
 	network := getTCPNetwork(task)
-	return &TCPProberTask{*task, network, dialer}
+	return &TCPProberTask{*task, network, dialer, 0, &sync.WaitGroup{}}
 }
 
-func (p *TCPProberTask) probe(ctx context.Context, attempt *uint64) (*time.Duration, error) {
-	target, err := p.beforeProbing(ctx, attempt)
+func getTCPInfo(conn net.Conn) (*tcpInfo, error) {
+	tcpConn, ok := conn.(*net.TCPConn)
+	if !ok {
+		return nil, syscall.EPROTOTYPE
+	}
+
+	rawConn, err := tcpConn.SyscallConn()
 	if err != nil {
-		target = p.Target
+		return nil, err
+	}
+
+	var info *unix.TCPInfo
+	var operr error
+	if err := rawConn.Control(func(fd uintptr) {
+		info, operr = unix.GetsockoptTCPInfo(int(fd), unix.IPPROTO_TCP, unix.TCP_INFO)
+	}); err != nil {
+		return nil, err
+	}
+	if operr != nil {
+		return nil, operr
+	}
+
+	return &tcpInfo{
+		RTT:         float64(info.Rtt) / float64(time.Millisecond/time.Microsecond),
+		RTTVariance: float64(info.Rttvar) / float64(time.Millisecond/time.Microsecond),
+		// no data has been sent yet, so all retransmissions are SYN retransmissions
+		SYNRetransmits:   info.Total_retrans,
+		MSS:              info.Snd_mss,
+		CongestionWindow: info.Snd_cwnd,
+	}, nil
+}
+
+// detectSYNLoss classifies connections whose latency matches the SYN retransmission schedule:
+// `TCP_INFO` confirms the retransmissions when available, otherwise only the latency is considered.
+func detectSYNLoss(latency time.Duration, info *tcpInfo) *synLoss {
+	if latency < synInitialRTO {
+		return nil
+	}
+
+	if info != nil && info.SYNRetransmits > 0 {
+		return &synLoss{Retransmits: info.SYNRetransmits, Confirmed: true}
+	}
+
+	var retransmits uint32
+	elapsed, rto := time.Duration(0), synInitialRTO
+	for elapsed+rto <= latency {
+		elapsed += rto
+		rto *= 2
+		retransmits += 1
 	}
 
+	// the handshake completed shortly after the last retransmission
+	if latency-elapsed > synRetransmitTolerance {
+		return nil
+	}
+
+	// the kernel had the chance to confirm it, but it did not
+	if info != nil {
+		return nil
+	}
+
+	return &synLoss{Retransmits: retransmits, Confirmed: false}
+}
+
+// closeConnection terminates a successful connection according to the task's `close_mode`;
+// for graceful modes, it reports how long it took for the peer to close its side.
+func closeConnection(conn net.Conn, closeMode string, timeout time.Duration) (*time.Duration, error) {
+	tcpConn, ok := conn.(*net.TCPConn)
+	if !ok || closeMode == CLOSE_MODE_RST {
+		return nil, conn.Close()
+	}
+
+	start := time.Now()
+	err := tcpConn.CloseWrite()
+	if err == nil {
+		tcpConn.SetReadDeadline(start.Add(timeout))
+		// anything the peer sends before its FIN is discarded
+		buffer := make([]byte, 512)
+		for err == nil {
+			_, err = tcpConn.Read(buffer)
+		}
+		if errors.Is(err, io.EOF) {
+			err = nil
+		}
+	}
+	latency := time.Since(start)
+
+	if closeMode == CLOSE_MODE_HALF_CLOSE {
+		tcpConn.SetLinger(0)
+	}
+
+	return &latency, errors.Join(err, tcpConn.Close())
+}
+
+// nextLocalAddr rotates through the configured source ports, if any
+func (p *TCPProberTask) nextLocalAddr() net.Addr {
+	var port uint16
+	if sourcePorts := p.Params.SourcePortRange; sourcePorts != nil {
+		// `overlap` probes and bursts pick their source ports concurrently
+		port = sourcePorts.port(atomic.AddUint64(&p.sourcePorts, 1) - 1)
+	}
+	return newLocalTCPAddr(p.Params, port)
+}
+
+// connect establishes a connection to `target` and describes how it went;
+// the connection is left open for the caller to decide how to terminate it.
+func (p *TCPProberTask) connect(ctx context.Context, target *netip.AddrPort) (net.Conn, *proberTaskData, error) {
+	return p.connectFrom(ctx, target, p.nextLocalAddr())
+}
+
+// connectFrom is safe to be used concurrently, as long as `localAddr` is not shared
+func (p *TCPProberTask) connectFrom(ctx context.Context, target *netip.AddrPort, localAddr net.Addr) (net.Conn, *proberTaskData, error) {
 	timeout := p.Params.Timeout
-	ctx, cancel := context.WithTimeout(ctx, timeout)
+	probeCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
+	dialer := *p.dialer
+	dialer.LocalAddr = localAddr
+
 	start := time.Now()
-	conn, err := p.dialer.DialContext(ctx, p.network, target.String())
+	conn, err := dialer.DialContext(probeCtx, p.network, target.String())
 	latency := time.Since(start)
 
+	data := &proberTaskData{latency: &latency}
+
+	if err == nil {
+		data.localAddr = conn.LocalAddr().String()
+		// `TCP_INFO` is best effort: it must not turn a successful probe into a failed one
+		data.tcpInfo, _ = getTCPInfo(conn)
+		data.synLoss = detectSYNLoss(latency, data.tcpInfo)
+	} else if dialer.LocalAddr != nil {
+		data.localAddr = dialer.LocalAddr.String()
+	}
+
+	if tcpAddr, ok := localAddr.(*net.TCPAddr); ok {
+		data.flowPort = uint16(tcpAddr.Port)
+	}
+
+	return conn, data, err
+}
+
+func (p *TCPProberTask) probe(ctx context.Context, attempt *uint64) (*time.Duration, error) {
+	target, err := p.beforeProbing(ctx, attempt)
+	if err != nil {
+		target = p.Target
+	}
+
+	if p.Params.Burst > 1 {
+		return p.probeBurst(ctx, attempt, target)
+	}
+
+	conn, data, err := p.connect(ctx, target)
 	if err == nil {
-		conn.Close()
+		data.closeLatency, data.closeErr = closeConnection(conn, p.Params.CloseMode, p.Params.Timeout)
 	}
 
-	p.afterProbing(ctx, attempt, target, &latency, err)
+	p.afterProbing(ctx, attempt, target, data, err)
 
-	return &latency, err
+	return data.latency, err
 }
@@ -0,0 +1,237 @@
+package prober
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"net/netip"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+type (
+	tracerouteHop struct {
+		TTL      uint8
+		Address  *netip.Addr
+		Latency  time.Duration
+		ICMPType uint8
+		ICMPCode uint8
+		Reached  bool
+		Err      error
+	}
+)
+
+const (
+	TRACEROUTE_TRIGGER_ON_DEMAND = "on_demand"
+	TRACEROUTE_TRIGGER_FAILURES  = "consecutive_failures"
+)
+
+const (
+	// https://www.iana.org/assignments/icmp-parameters
+	icmpv4DestUnreachable uint8 = 3
+	// https://www.iana.org/assignments/icmpv6-parameters
+	icmpv6DestUnreachable uint8 = 1
+
+	sizeOfSockExtendedErr = 16
+)
+
+func setTracerouteSocketOptions(task *proberTask, fd uintptr, IPv6 bool, ttl uint8, errQueueFD *int, err *error) {
+	var ttlErr, recvErrErr error
+	if IPv6 {
+		ttlErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_IPV6, unix.IPV6_UNICAST_HOPS, int(ttl))
+		recvErrErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_IPV6, unix.IPV6_RECVERR, 1)
+	} else {
+		ttlErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_IP, unix.IP_TTL, int(ttl))
+		recvErrErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_IP, unix.IP_RECVERR, 1)
+	}
+	lingerErr := syscall.SetsockoptLinger(int(fd), unix.SOL_SOCKET, unix.SO_LINGER, _SO_LINGER)
+	deviceErr := bindToDevice(task, fd)
+	trafficErr := setTrafficOptions(task, fd, IPv6)
+	// the dialer closes the socket when connecting fails, so a duplicate is kept
+	// to be able to read the ICMP error that caused the failure from the error queue.
+	dupFD, dupErr := unix.FcntlInt(fd, unix.F_DUPFD_CLOEXEC, 0)
+	if dupErr == nil {
+		*errQueueFD = dupFD
+	}
+	*err = errors.Join(ttlErr, recvErrErr, lingerErr, deviceErr, trafficErr, dupErr)
+}
+
+func parseOffender(offender []byte) (*netip.Addr, bool) {
+	if len(offender) < 2 {
+		return nil, false
+	}
+	switch binary.NativeEndian.Uint16(offender[0:2]) {
+	case unix.AF_INET:
+		if len(offender) < 8 {
+			return nil, false
+		}
+		IP := netip.AddrFrom4([4]byte(offender[4:8]))
+		return &IP, true
+	case unix.AF_INET6:
+		if len(offender) < 24 {
+			return nil, false
+		}
+		IP := netip.AddrFrom16([16]byte(offender[8:24]))
+		return &IP, true
+	}
+	return nil, false
+}
+
+// readErrorQueue extracts the ICMP error reported by the kernel for a failed connection attempt.
+// see: https://man7.org/linux/man-pages/man7/ip.7.html ( `IP_RECVERR` )
+func readErrorQueue(fd int, hop *tracerouteHop) bool {
+	oob := make([]byte, 512)
+	_, oobn, _, _, err := unix.Recvmsg(fd, nil, oob, unix.MSG_ERRQUEUE|unix.MSG_DONTWAIT)
+	if err != nil {
+		return false
+	}
+
+	messages, err := unix.ParseSocketControlMessage(oob[:oobn])
+	if err != nil {
+		return false
+	}
+
+	for _, message := range messages {
+		isIPv4Error := message.Header.Level == unix.SOL_IP && message.Header.Type == unix.IP_RECVERR
+		isIPv6Error := message.Header.Level == unix.SOL_IPV6 && message.Header.Type == unix.IPV6_RECVERR
+		if !(isIPv4Error || isIPv6Error) || len(message.Data) < sizeOfSockExtendedErr {
+			continue
+		}
+		// https://man7.org/linux/man-pages/man7/ip.7.html ( `struct sock_extended_err` )
+		if message.Data[4] != unix.SO_EE_ORIGIN_ICMP && message.Data[4] != unix.SO_EE_ORIGIN_ICMP6 {
+			continue
+		}
+		hop.ICMPType = message.Data[5]
+		hop.ICMPCode = message.Data[6]
+		if IP, ok := parseOffender(message.Data[sizeOfSockExtendedErr:]); ok {
+			hop.Address = IP
+		}
+		return true
+	}
+
+	return false
+}
+
+func isDestinationUnreachable(hop *tracerouteHop, IPv6 bool) bool {
+	if IPv6 {
+		return hop.ICMPType == icmpv6DestUnreachable
+	}
+	return hop.ICMPType == icmpv4DestUnreachable
+}
+
+func (pt *proberTask) probeHop(ctx context.Context, network string, target *netip.AddrPort, ttl uint8) *tracerouteHop {
+	hop := &tracerouteHop{TTL: ttl}
+
+	IPv6 := target.Addr().Is6()
+	errQueueFD := -1
+
+	// source ports are left to the kernel as tracing runs concurrently with probing
+	dialer := &net.Dialer{
+		Timeout:   pt.Params.Timeout,
+		LocalAddr: newLocalTCPAddr(pt.Params, 0),
+		Control: func(network, address string, conn syscall.RawConn) error {
+			var operr error
+			if err := conn.Control(func(fd uintptr) {
+				setTracerouteSocketOptions(pt, fd, IPv6, ttl, &errQueueFD, &operr)
+			}); err != nil {
+				return err
+			}
+			return operr
+		},
+	}
+
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, network, target.String())
+	hop.Latency = time.Since(start)
+
+	if errQueueFD >= 0 {
+		defer unix.Close(errQueueFD)
+	}
+
+	if err == nil {
+		conn.Close()
+	}
+
+	// both a `SYN+ACK` and a `RST` mean that the SYN made it all the way to the target
+	if err == nil || errors.Is(err, syscall.ECONNREFUSED) {
+		IP := target.Addr()
+		hop.Address = &IP
+		hop.Reached = true
+		return hop
+	}
+
+	if errQueueFD >= 0 && readErrorQueue(errQueueFD, hop) {
+		hop.Reached = hop.Address != nil && *hop.Address == target.Addr()
+		if isDestinationUnreachable(hop, IPv6) {
+			hop.Err = err
+		}
+		return hop
+	}
+
+	// no ICMP response was received for this TTL: `*`
+	hop.Err = err
+	return hop
+}
+
+func (pt *proberTask) traceHops(ctx context.Context, target *netip.AddrPort) []*tracerouteHop {
+	network := getTCPNetwork(pt)
+	maxHops := pt.Params.TracerouteMaxHops
+
+	hops := make([]*tracerouteHop, 0, maxHops)
+	for ttl := uint8(1); ttl > 0 && ttl <= maxHops; ttl++ {
+		if ctx.Err() != nil {
+			break
+		}
+		hop := pt.probeHop(ctx, network, target, ttl)
+		hops = append(hops, hop)
+		if hop.Reached || isDestinationUnreachable(hop, target.Addr().Is6()) {
+			break
+		}
+	}
+	return hops
+}
+
+// traceroute discovers the path to the current target by sending SYNs with increasing TTLs;
+// the target is read under the task's mutex, as `overlap` probes may be updating it.
+func (pt *proberTask) traceroute(ctx context.Context, trigger string) {
+	pt.mutex.Lock()
+	target := *pt.Target
+	pt.mutex.Unlock()
+
+	pt.traceTo(ctx, target, trigger)
+}
+
+// traceTo discovers the path to `target` asynchronously; it does not touch the task's mutex,
+// so it may be called while holding it.
+func (pt *proberTask) traceTo(ctx context.Context, target netip.AddrPort, trigger string) {
+	if pt.Type == UNIX {
+		return // there is no network path to a unix socket
+	}
+
+	if !pt.tracing.CompareAndSwap(false, true) {
+		return // a traceroute is already in progress
+	}
+
+	go func() {
+		defer pt.tracing.Store(false)
+		hops := pt.traceHops(ctx, &target)
+		(*pt.Printer).printTraceroute(pt, &target, hops, trigger)
+	}()
+}
+
+func (pt *proberTask) tracerouteRequests() chan struct{} {
+	return pt.onDemandTraceroute
+}
+
+// Traceroute requests the prober to discover the path towards its target
+// the next time it is idle; requests received while one is pending are dropped.
+func Traceroute(prober *Prober) {
+	p := *prober
+	select {
+	case p.tracerouteRequests() <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,47 @@
+package prober
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+type (
+	UnixProberTask struct {
+		proberTask
+		dialer *net.Dialer
+	}
+)
+
+func newUnixProberTask(task *proberTask) Prober {
+	dialer := &net.Dialer{
+		Timeout: task.Params.Timeout,
+	}
+	return &UnixProberTask{*task, dialer}
+}
+
+func (p *UnixProberTask) probe(ctx context.Context, attempt *uint64) (*time.Duration, error) {
+	target, err := p.beforeProbing(ctx, attempt)
+	if err != nil {
+		target = p.Target
+	}
+
+	timeout := p.Params.Timeout
+	probeCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	conn, err := p.dialer.DialContext(probeCtx, "unix", p.URL.Path)
+	if err == nil {
+		err = exchangePayload(conn, p.Params, start.Add(timeout))
+	}
+	latency := time.Since(start)
+
+	if conn != nil {
+		conn.Close()
+	}
+
+	p.afterProbing(ctx, attempt, target, &proberTaskData{latency: &latency}, err)
+
+	return &latency, err
+}